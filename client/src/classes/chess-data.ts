@@ -4,6 +4,7 @@ export type ChessInfo = {
     statusMessage: string;
     turn: boolean;
     gameEnd: string;
+    spectators: number;
 };
 
 export type MoveSearch = {
@@ -15,6 +16,16 @@ export type ChessMove = {
     moveType: string;
     oldSquare: number;
     newSquare: number;
+    san?: string;
+    uci?: string;
+};
+
+// milliseconds left for each side, only sent for timed games
+export type ChessClock = {
+    white: number;
+    black: number;
+    running: "white" | "black" | "";
+    timeControl: string;
 };
 
 export type ChessState = {
@@ -22,6 +33,9 @@ export type ChessState = {
     board: number[];
     possibleMoves: ChessMove[];
     previousMoves: ChessMove[];
+    clock?: ChessClock;
+    // number of people watching the game
+    spectators: number;
 };
 
 export type ChessMessage = {
@@ -43,4 +57,43 @@ export const DefaultChessState: ChessState = {
     ],
     previousMoves: [],
     possibleMoves: [],
-};
\ No newline at end of file
+    spectators: 0,
+};
+
+// keeps only the query parameters describing the clock
+export const timeControlParams = (searchParams: URLSearchParams) => {
+    const params = new URLSearchParams();
+    for (const key of ["minutes", "increment", "mode"]) {
+        const value = searchParams.get(key);
+        if (value) {
+            params.set(key, value);
+        }
+    }
+    return params.toString();
+};
+// a game as it is listed in the lobby
+export type LobbyGame = {
+    id: string;
+    status: "open" | "playing" | "finished";
+    players: [string, string];
+    rated: boolean;
+    timeControl: string;
+    moves: number;
+    turn: "white" | "black" | "";
+    result?: string;
+    created: string;
+    ended?: string;
+};
+
+export type LobbyPage = {
+    games: LobbyGame[];
+    total: number;
+    offset: number;
+    limit: number;
+};
+
+// pushed by the lobby feed as games are created, start and end
+export type LobbyEvent = {
+    type: "created" | "started" | "ended";
+    game: LobbyGame;
+};
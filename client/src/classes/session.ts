@@ -0,0 +1,38 @@
+const tokenKey = "sessionToken";
+const usernameKey = "username";
+
+export const serverURL = "http://localhost:3000";
+
+export type Session = {
+    token: string | null;
+    username: string | null;
+};
+
+// the session of the logged in user, kept across page loads
+export const getSession = (): Session => ({
+    token: localStorage.getItem(tokenKey),
+    username: localStorage.getItem(usernameKey),
+});
+
+export const saveSession = (token: string, username: string) => {
+    localStorage.setItem(tokenKey, token);
+    localStorage.setItem(usernameKey, username);
+};
+
+export const clearSession = () => {
+    localStorage.removeItem(tokenKey);
+    localStorage.removeItem(usernameKey);
+};
+
+// headers authenticating a request as the logged in user
+export const authHeaders = (): HeadersInit => {
+    const token = getSession().token;
+    return token ? { Authorization: `Bearer ${token}` } : {};
+};
+
+// token for taking back a seat in a game after losing the connection, kept for the browser tab
+export const getSeatToken = (gameID: string): string | null => sessionStorage.getItem("seat-" + gameID);
+
+export const saveSeatToken = (gameID: string, token: string) => {
+    sessionStorage.setItem("seat-" + gameID, token);
+};
@@ -3,6 +3,7 @@ import "../styles/chess-connection.css"
 import { useCallback, useEffect, useState } from "react";
 import { ChessInfo, ChessMessage, ChessMove, ChessState, DefaultChessState, MoveSearch } from "../classes/chess-data";
 import ChessGame from "./chess-game";
+import { getSeatToken, getSession, saveSeatToken } from "../classes/session";
 
 type ChessConnectionProps = {
     gameID: string;
@@ -14,8 +15,25 @@ const ChessConnection = ({ gameID }: ChessConnectionProps) => {
     const [gameEnd, setGameEnd] = useState("Continuing")
     const [statusMessage, setStatusMessage] = useState("Normal")
 
-    const url = "ws://localhost:3000/game/" + gameID;
-    const { sendJsonMessage, lastJsonMessage, readyState } = useWebSocket<ChessMessage>(url);
+    // browsers cannot set headers on websockets, so the session token goes in the URL
+    // along with the seat token, if there is one, so reconnecting takes back the same seat
+    const getSocketUrl = useCallback(() => {
+        const params = new URLSearchParams();
+        const token = getSession().token;
+        if (token) {
+            params.set("token", token);
+        }
+        const seat = getSeatToken(gameID);
+        if (seat) {
+            params.set("seat", seat);
+        }
+        return "ws://localhost:3000/game/" + gameID + "?" + params.toString();
+    }, [gameID]);
+    const { sendJsonMessage, lastJsonMessage, readyState } = useWebSocket<ChessMessage>(getSocketUrl, {
+        shouldReconnect: () => gameEnd == "Continuing",
+        reconnectAttempts: 30,
+        reconnectInterval: 1000,
+    });
 
     const handleSendMove = useCallback((move: ChessMove) => sendJsonMessage(move), []);
     const handleSearchMove = useCallback((index: number) => {
@@ -41,6 +59,15 @@ const ChessConnection = ({ gameID }: ChessConnectionProps) => {
                 console.log("Recieving miscMessage");
                 setStatusMessage(lastJsonMessage.messageContent)
                 console.log(statusMessage);
+            } else if (lastJsonMessage.messageType == 3) {
+                console.log("Recieving seat token");
+                saveSeatToken(gameID, lastJsonMessage.messageContent);
+            } else if (lastJsonMessage.messageType == 4) {
+                setStatusMessage("Opponent disconnected, " + lastJsonMessage.messageContent + "s to reconnect");
+            } else if (lastJsonMessage.messageType == 5) {
+                // only the number of spectators changed, the rest of the state is unchanged
+                const spectators = Number(lastJsonMessage.messageContent);
+                setGameState(state => ({ ...state, spectators: spectators }));
             }
         }
     }, [lastJsonMessage]);
@@ -59,6 +86,7 @@ const ChessConnection = ({ gameID }: ChessConnectionProps) => {
         statusMessage: statusMessage,
         turn: gameState.turn,
         gameEnd: gameEnd,
+        spectators: gameState.spectators,
     };
 
     return (
@@ -0,0 +1,46 @@
+import { useEffect, useState } from "react";
+import { ChessClock } from "../classes/chess-data";
+
+export type ChessGameClockProps = {
+    clock: ChessClock;
+    stopped: boolean;
+};
+
+// shows milliseconds as minutes and seconds, with tenths under ten seconds
+const formatTime = (milliseconds: number) => {
+    const time = Math.max(milliseconds, 0);
+    const minutes = Math.floor(time / 60000);
+    const seconds = Math.floor(time / 1000) % 60;
+    if (time < 10000) {
+        return `${seconds}.${Math.floor(time / 100) % 10}`;
+    }
+    return `${minutes}:${seconds.toString().padStart(2, "0")}`;
+};
+
+// counts down the running side locally between updates from the server
+const ChessGameClock = ({ clock, stopped }: ChessGameClockProps) => {
+    const [elapsed, setElapsed] = useState(0);
+
+    useEffect(() => {
+        setElapsed(0);
+        if (stopped || clock.running === "") {
+            return;
+        }
+        const received = Date.now();
+        const interval = setInterval(() => setElapsed(Date.now() - received), 100);
+        return () => clearInterval(interval);
+    }, [clock, stopped]);
+
+    const white = clock.running === "white" ? clock.white - elapsed : clock.white;
+    const black = clock.running === "black" ? clock.black - elapsed : clock.black;
+
+    return (
+        <div>
+            <p>Time Control: {clock.timeControl}</p>
+            <p>White: {formatTime(white)}</p>
+            <p>Black: {formatTime(black)}</p>
+        </div>
+    );
+};
+
+export default ChessGameClock;
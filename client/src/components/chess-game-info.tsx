@@ -1,11 +1,13 @@
-import { ChessInfo } from "../classes/chess-data";
+import { ChessClock, ChessInfo } from "../classes/chess-data";
+import ChessGameClock from "./chess-game-clock";
 import "../styles/chess-game-info.css";
 
 export type ChessGameInfoProps = {
     gameInfo: ChessInfo;
+    clock?: ChessClock;
 };
 
-const ChessGameInfo = ({ gameInfo }: ChessGameInfoProps) => {
+const ChessGameInfo = ({ gameInfo, clock }: ChessGameInfoProps) => {
 
     const turn: string = gameInfo.turn ? "White" : "Black";
 
@@ -15,10 +17,12 @@ const ChessGameInfo = ({ gameInfo }: ChessGameInfoProps) => {
                 <div>
                     <h1 className="info-header">Game Info</h1>
                     {gameInfo.gameEnd == "Continuing" ? <p>Turn: {turn}</p> : <p>{gameInfo.gameEnd}</p>}
+                    {clock && <ChessGameClock clock={clock} stopped={gameInfo.gameEnd != "Continuing"}/>}
                 </div>
                 <div>
                     <p>Status: {gameInfo.statusMessage}</p>
                     <p>Connection: {gameInfo.connectionStatus}</p>
+                    {gameInfo.spectators > 0 && <p>Spectators: {gameInfo.spectators}</p>}
                     <p>GameID: {gameInfo.gameID}</p>
                 </div> 
             </div>
@@ -9,6 +9,10 @@ export type ChessGameMovesProps = {
 const ChessGameMoves = ({ moves, handleMoveClick }: ChessGameMovesProps) => {
     
     const movesOutput: string[] = moves.map<string>((move) => {
+       // prefer the algebraic notation sent by the server
+       if (move.san) {
+           return move.san;
+       }
        // translate moves to string coded move
        const stringMove = move.oldSquare.toString() + "-" + move.newSquare.toString()
        return stringMove; 
@@ -15,7 +15,7 @@ const ChessGame = ({ gameState, moveHandler, gameInfo, moveClickHandler }: Chess
 
     return (
         <div className="chess-game-container">
-            <ChessGameInfo gameInfo={gameInfo}/>
+            <ChessGameInfo gameInfo={gameInfo} clock={gameState.clock}/>
             <ChessBoard sideColor={0} boardState={gameState} moveHandler={moveHandler}/>
             <ChessGameMoves moves={gameState.previousMoves} handleMoveClick={moveClickHandler}/>
         </div>
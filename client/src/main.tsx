@@ -11,6 +11,9 @@ import PlayComputer from './routes/play-computer'
 import PlayOnline from './routes/play-online'
 import Home from './routes/home-page'
 import Settings from './routes/settings-page'
+import Login from './routes/login-page'
+import Lobby from './routes/lobby-page'
+import WatchGame from './routes/watch-game'
 
 const router = createBrowserRouter([
   {
@@ -30,10 +33,22 @@ const router = createBrowserRouter([
         path: "/play/online",
         element: <PlayOnline />,
       },
+      {
+        path: "/lobby",
+        element: <Lobby />,
+      },
+      {
+        path: "/watch/:gameID",
+        element: <WatchGame />,
+      },
       {
         path: "/settings",
         element: <Settings />,
       },
+      {
+        path: "/login",
+        element: <Login />,
+      },
     ],
   },
 ]);
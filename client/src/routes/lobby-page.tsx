@@ -0,0 +1,90 @@
+import "../styles/lobby-page.css"
+import { useEffect, useState } from "react";
+import { Link } from "react-router-dom";
+import useWebSocket from "react-use-websocket";
+import { LobbyEvent, LobbyPage } from "../classes/chess-data";
+import { serverURL } from "../classes/session";
+
+const pageSize = 20;
+
+const listGames = async (status: string, offset: number) => {
+    const params = new URLSearchParams({ offset: String(offset), limit: String(pageSize) });
+    if (status) {
+        params.set("status", status);
+    }
+    const response = await fetch(`${serverURL}/games?${params.toString()}`);
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return await response.json() as LobbyPage;
+};
+
+const Lobby = () => {
+    const [status, setStatus] = useState("");
+    const [offset, setOffset] = useState(0);
+    const [page, setPage] = useState<LobbyPage | null>(null);
+    const [error, setError] = useState("");
+
+    // the feed says when games come and go, so the list is fetched again
+    const { lastJsonMessage } = useWebSocket<LobbyEvent>("ws://localhost:3000/lobby", {
+        shouldReconnect: () => true,
+    });
+
+    useEffect(() => {
+        listGames(status, offset).then(
+            result => setPage(result),
+            (e: Error) => setError(e.message),
+        );
+    }, [status, offset, lastJsonMessage]);
+
+    const changeStatus = (newStatus: string) => {
+        setStatus(newStatus);
+        setOffset(0);
+    };
+
+    return (
+        <div className="lobby-container">
+            <h1>Lobby</h1>
+            <select value={status} onChange={e => changeStatus(e.target.value)}>
+                <option value="">All games</option>
+                <option value="open">Open</option>
+                <option value="playing">Playing</option>
+                <option value="finished">Finished</option>
+            </select>
+            {error && <p>{error}</p>}
+            <table className="lobby-table">
+                <thead>
+                    <tr>
+                        <th>White</th>
+                        <th>Black</th>
+                        <th>Time</th>
+                        <th>Moves</th>
+                        <th>Status</th>
+                        <th></th>
+                    </tr>
+                </thead>
+                <tbody>
+                    {page?.games.map(game => (
+                        <tr key={game.id}>
+                            <td>{game.players[0]}</td>
+                            <td>{game.players[1]}</td>
+                            <td>{game.timeControl}{game.rated && " rated"}</td>
+                            <td>{game.moves}</td>
+                            <td>{game.status == "finished" ? game.result : game.status == "playing" ? game.turn + " to move" : "open"}</td>
+                            <td>{game.status != "finished" && <Link to={`/watch/${game.id}`}>Watch</Link>}</td>
+                        </tr>
+                    ))}
+                </tbody>
+            </table>
+            {page && (
+                <div className="lobby-pages">
+                    <button disabled={offset == 0} onClick={() => setOffset(Math.max(0, offset - pageSize))}>Previous</button>
+                    <span>{page.total == 0 ? 0 : offset + 1}-{Math.min(offset + pageSize, page.total)} of {page.total}</span>
+                    <button disabled={offset + pageSize >= page.total} onClick={() => setOffset(offset + pageSize)}>Next</button>
+                </div>
+            )}
+        </div>
+    );
+};
+
+export default Lobby;
@@ -0,0 +1,74 @@
+import { FormEvent, useState } from "react";
+import { useNavigate } from "react-router-dom";
+import { authHeaders, clearSession, getSession, saveSession, serverURL } from "../classes/session";
+import "../styles/login-page.css"
+
+const login = async (username: string, password: string) => {
+    const response = await fetch(`${serverURL}/login`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ username, password }),
+    });
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    const session = await response.json();
+    saveSession(session.token, session.username);
+};
+
+const register = async (username: string, password: string) => {
+    const response = await fetch(`${serverURL}/register`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ username, password }),
+    });
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    await login(username, password);
+};
+
+const Login = () => {
+    const [username, setUsername] = useState("");
+    const [password, setPassword] = useState("");
+    const [message, setMessage] = useState("");
+    const [loggedInAs, setLoggedInAs] = useState(getSession().username);
+    const navigate = useNavigate();
+
+    const submit = (action: (username: string, password: string) => Promise<void>) => (e: FormEvent) => {
+        e.preventDefault();
+        action(username, password).then(
+            () => navigate("/home"),
+            (error: Error) => setMessage(error.message),
+        );
+    };
+
+    const logout = () => {
+        fetch(`${serverURL}/logout`, { method: "POST", headers: authHeaders() }).finally(() => {
+            clearSession();
+            setLoggedInAs(null);
+        });
+    };
+
+    if (loggedInAs) {
+        return (
+            <div className="login-container">
+                <h1>Logged in as {loggedInAs}</h1>
+                <button onClick={logout}>Log Out</button>
+            </div>
+        );
+    }
+
+    return (
+        <form className="login-container" onSubmit={submit(login)}>
+            <h1>Log In</h1>
+            <input type="text" placeholder="Username" value={username} onChange={e => setUsername(e.target.value)} />
+            <input type="password" placeholder="Password" value={password} onChange={e => setPassword(e.target.value)} />
+            <button type="submit">Log In</button>
+            <button type="button" onClick={submit(register)}>Register</button>
+            {message && <p>{message}</p>}
+        </form>
+    );
+};
+
+export default Login;
@@ -1,28 +1,46 @@
 import "../styles/play-computer.css"
 import { useEffect, useState } from "react";
+import { Link, useSearchParams } from "react-router-dom";
 import ChessConnection from "../components/chess-connection";
+import { timeControlParams } from "../classes/chess-data";
+import { authHeaders } from "../classes/session";
 
 const findGameEndpoint = "http://localhost:3000/findGame/1"
 
-const findGame = async () => {
-    const response = await fetch(findGameEndpoint);
+// difficulty and time control are passed through from the page, e.g. /play/computer?level=3&minutes=5
+const findGame = async (level: string | null, timeControl: string) => {
+    const params = new URLSearchParams(timeControl);
+    if (level) {
+        params.set("level", level);
+    }
+    const query = params.toString();
+    const response = await fetch(query ? `${findGameEndpoint}?${query}` : findGameEndpoint, { headers: authHeaders() });
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
     const jsonResponse = await response.json();
     return JSON.stringify(jsonResponse);
 }
 
 const PlayComputer = () => {
     const [gameID, setGameID] = useState("...")
+    const [error, setError] = useState("");
+    const [searchParams] = useSearchParams();
+    const level = searchParams.get("level");
+    const timeControl = timeControlParams(searchParams);
 
     useEffect(() => {
-        findGame().then(
-            result => setGameID(result)
+        findGame(level, timeControl).then(
+            result => setGameID(result),
+            (e: Error) => setError(e.message),
         );
-    }, []);
+    }, [level, timeControl]);
 
     
 
     return (
         <div className="play-computer-container">
+            {error && <p>{error} <Link to="/login">Log in</Link></p>}
             {gameID !== "..." && <ChessConnection gameID={gameID}/>}
         </div>
     );
@@ -1,29 +1,42 @@
 import "../styles/play-online.css"
 import { useEffect, useState } from "react";
+import { Link, useSearchParams } from "react-router-dom";
 import ChessConnection from "../components/chess-connection";
+import { timeControlParams } from "../classes/chess-data";
+import { authHeaders } from "../classes/session";
 
 const findGameEndpoint = "http://localhost:3000/findGame/2"
 
-const findGame = async () => {
-    const response = await fetch(findGameEndpoint);
+// the time control is passed through from the page, e.g. /play/online?minutes=5&increment=3
+// the logged in player is matched with an opponent of a similar rating
+const findGame = async (timeControl: string) => {
+    const response = await fetch(timeControl ? `${findGameEndpoint}?${timeControl}` : findGameEndpoint, { headers: authHeaders() });
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
     const jsonResponse = await response.json();
     return JSON.stringify(jsonResponse);
 }
 
 const PlayOnline = () => {
     const [gameID, setGameID] = useState("...")
+    const [error, setError] = useState("");
+    const [searchParams] = useSearchParams();
+    const timeControl = timeControlParams(searchParams);
 
     useEffect(() => {
-        findGame().then(
-            result => setGameID(result)
+        findGame(timeControl).then(
+            result => setGameID(result),
+            (e: Error) => setError(e.message),
         );
-    }, []);
+    }, [timeControl]);
 
     return (
         <div className="play-online-container">
+            {error && <p>{error} <Link to="/login">Log in</Link></p>}
             {gameID !== "..." && <ChessConnection gameID={gameID}/>}
         </div>
     );
 };
 
-export default PlayOnline;
\ No newline at end of file
+export default PlayOnline;
@@ -9,7 +9,9 @@ const Root = () => {
                     <Link to="/home" className="main-nav-link">Home</Link>
                     <Link to="/play/computer" className="main-nav-link">Play Computer</Link>
                     <Link to="/play/online" className="main-nav-link">Play Online</Link>
+                    <Link to="/lobby" className="main-nav-link">Lobby</Link>
                 </div>
+                <Link to="/login" className="minor-nav-link">Account</Link>
                 <Link to="/settings" className="minor-nav-link">Settings</Link>
             </nav>
             <div className="content-view">
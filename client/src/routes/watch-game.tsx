@@ -0,0 +1,16 @@
+import "../styles/play-online.css"
+import { useParams } from "react-router-dom";
+import ChessConnection from "../components/chess-connection";
+
+// joins a game from the lobby, the server seats players it is reserved for and everyone else watches
+const WatchGame = () => {
+    const { gameID } = useParams();
+
+    return (
+        <div className="play-online-container">
+            {gameID && <ChessConnection gameID={gameID}/>}
+        </div>
+    );
+};
+
+export default WatchGame;
@@ -2,11 +2,15 @@ module github.com/BrianJHenry/go-chess
 
 go 1.20
 
-require github.com/gofiber/fiber/v2 v2.46.0
+require (
+	github.com/gofiber/fiber/v2 v2.46.0
+	go.etcd.io/bbolt v1.3.7
+	golang.org/x/crypto v0.9.0
+)
 
 require (
 	github.com/andybalholm/brotli v1.0.5 // indirect
-	github.com/fasthttp/websocket v1.5.3 // indirect
+	github.com/fasthttp/websocket v1.5.3
 	github.com/gofiber/contrib/websocket v1.0.0
 	github.com/google/uuid v1.3.0 // indirect
 	github.com/klauspost/compress v1.16.5 // indirect
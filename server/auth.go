@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/accounts"
+	"github.com/gofiber/fiber/v2"
+)
+
+const sessionCookie = "session"
+
+var accountStore *accounts.Store
+
+// username and password sent to /register and /login, as JSON or a form
+type credentials struct {
+	Username string `json:"username" form:"username"`
+	Password string `json:"password" form:"password"`
+}
+
+// returns the session token from the Authorization header, the session cookie,
+// or the token query parameter used by websockets since browsers cannot set their headers
+func sessionToken(c *fiber.Ctx) string {
+	if header := c.Get(fiber.HeaderAuthorization); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	if cookie := c.Cookies(sessionCookie); cookie != "" {
+		return cookie
+	}
+	return c.Query("token")
+}
+
+// returns the user logged in to the request's session
+func currentUser(c *fiber.Ctx) (accounts.User, error) {
+	return accountStore.Authenticate(sessionToken(c))
+}
+
+// rejects requests without a valid session and stores the user's name in the "username" local
+func requireUser(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return c.Status(401).SendString("You must be logged in.")
+	}
+	c.Locals("username", user.Username)
+	return c.Next()
+}
+
+func setupAuthRoutes(app *fiber.App) {
+
+	app.Post("/register", func(c *fiber.Ctx) error {
+		var body credentials
+		if err := c.BodyParser(&body); err != nil {
+			log.Println("Invalid Register Request")
+			return c.Status(404).SendString(err.Error())
+		}
+		user, err := accountStore.Register(body.Username, body.Password)
+		if err == accounts.ErrUsernameTaken {
+			return c.Status(409).SendString(err.Error())
+		}
+		if err == accounts.ErrInvalidUsername || err == accounts.ErrInvalidPassword {
+			return c.Status(404).SendString(err.Error())
+		}
+		if err != nil {
+			log.Println(err)
+			return c.Status(500).SendString("Could not create the account.")
+		}
+		log.Printf("Registered %v", user.Username)
+		return c.JSON(user)
+	})
+
+	// starts a session, returning its token and setting it as a cookie
+	app.Post("/login", func(c *fiber.Ctx) error {
+		var body credentials
+		if err := c.BodyParser(&body); err != nil {
+			log.Println("Invalid Login Request")
+			return c.Status(404).SendString(err.Error())
+		}
+		token, user, err := accountStore.Login(body.Username, body.Password)
+		if err == accounts.ErrInvalidCredentials {
+			return c.Status(401).SendString(err.Error())
+		}
+		if err != nil {
+			log.Println(err)
+			return c.Status(500).SendString("Could not log in.")
+		}
+		c.Cookie(&fiber.Cookie{
+			Name:     sessionCookie,
+			Value:    token,
+			Expires:  time.Now().Add(accounts.SessionLifetime),
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+		return c.JSON(fiber.Map{"token": token, "username": user.Username})
+	})
+
+	app.Post("/logout", func(c *fiber.Ctx) error {
+		if err := accountStore.Logout(sessionToken(c)); err != nil {
+			log.Println(err)
+			return c.Status(500).SendString("Could not log out.")
+		}
+		c.ClearCookie(sessionCookie)
+		return c.SendStatus(204)
+	})
+
+	app.Get("/me", func(c *fiber.Ctx) error {
+		user, err := currentUser(c)
+		if err != nil {
+			return c.Status(401).SendString("You must be logged in.")
+		}
+		return c.JSON(user)
+	})
+}
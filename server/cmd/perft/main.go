@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+func main() {
+	fen := flag.String("fen", models.StartingFEN, "position to search, in FEN")
+	depth := flag.Int("depth", 4, "search depth in plies")
+	divide := flag.Bool("divide", false, "print the node count below each root move")
+	generator := flag.String("generator", "mailbox", "move generator to use, \"mailbox\" or \"bitboard\"")
+	flag.Parse()
+
+	state, err := models.NewChessStateFromFEN(*fen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch *generator {
+	case "mailbox":
+		state.SetMoveGenerator(models.MailboxGenerator)
+	case "bitboard":
+		state.SetMoveGenerator(models.BitboardGenerator)
+	default:
+		log.Fatalf("unknown move generator %q", *generator)
+	}
+
+	start := time.Now()
+	var nodes int64
+	if *divide {
+		counts := state.Divide(*depth)
+		moves := make([]string, 0, len(counts))
+		for move := range counts {
+			moves = append(moves, move)
+		}
+		sort.Strings(moves)
+		for _, move := range moves {
+			fmt.Printf("%s: %d\n", move, counts[move])
+			nodes += counts[move]
+		}
+		fmt.Println()
+	} else {
+		nodes = state.Perft(*depth)
+	}
+	elapsed := time.Since(start)
+
+	fmt.Printf("Nodes: %d\n", nodes)
+	fmt.Printf("Time: %v\n", elapsed.Round(time.Millisecond))
+	if elapsed > 0 {
+		fmt.Printf("NPS: %.0f\n", float64(nodes)/elapsed.Seconds())
+	}
+}
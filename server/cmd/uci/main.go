@@ -0,0 +1,16 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/uci"
+)
+
+// runs the engine as a UCI engine on stdin and stdout, for use with chess GUIs and tournament managers
+func main() {
+	handler := uci.NewHandler(os.Stdout)
+	if err := handler.Run(os.Stdin); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/engine"
+	"github.com/BrianJHenry/go-chess/server/pkg/lobby"
+	"github.com/BrianJHenry/go-chess/server/pkg/matchmaking"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	"github.com/BrianJHenry/go-chess/server/pkg/sockets"
+	"github.com/BrianJHenry/go-chess/server/pkg/storage"
+)
+
+// the games being played
+var games = sockets.NewRegistry()
+
+// every game played, including the ones that have ended
+var gameStore *storage.Store
+
+// players in the matchmaking queue and games being played and recently finished, listed by /games and pushed to /lobby
+var gameLobby = lobby.New(func() []matchmaking.Seek { return matchmaker.Seeks() })
+
+// creates the game described by a record, with open seats kept for the human players, and starts it
+// a record without an ID is a new game, which is given an ID and stored, otherwise the stored game is continued
+func startGame(record storage.GameRecord) (*sockets.Game, error) {
+	var players [2]sockets.Player
+	var reserved [2]string
+	for seat, playerRecord := range record.Players {
+		if !playerRecord.Computer {
+			reserved[seat] = playerRecord.Name
+			continue
+		}
+		level, err := engine.LevelByNumber(playerRecord.Level)
+		if err != nil {
+			return nil, err
+		}
+		computer, err := newComputerOpponent(playerRecord.Bot, level)
+		if err != nil {
+			return nil, err
+		}
+		players[seat] = sockets.NewComputerPlayer(computer, level)
+		record.Players[seat].Name = players[seat].Name()
+	}
+
+	restoring := record.ID != ""
+	if !restoring {
+		record.ID = games.ReserveID(gameStore.Exists)
+		record.Created = time.Now()
+		if err := gameStore.CreateGame(record); err != nil {
+			games.Release(record.ID)
+			closePlayers(players)
+			return nil, err
+		}
+	}
+
+	var newGame *sockets.Game
+	newGame = sockets.NewGame(record.ID, func(gameID string) {
+		log.Printf("Game %v finished", gameID)
+		if record.Rated {
+			updated, err := ratings.RecordGame(record.Players[0].Name, record.Players[1].Name, newGame.ChessGame().Winner)
+			if err != nil {
+				log.Printf("Could not save ratings of %v and %v: %v", record.Players[0].Name, record.Players[1].Name, err)
+			} else if updated {
+				log.Printf("Updated ratings of %v and %v", record.Players[0].Name, record.Players[1].Name)
+			}
+		}
+	}, players, record.TimeControl)
+	newGame.StartTime = record.Created
+	newGame.Reserved = reserved
+	newGame.Recorder = gameStore
+	newGame.ReconnectGrace = *reconnectGrace
+	newGame.StartTimeout = *startTimeout
+	if restoring {
+		chessGame, err := record.ChessGame()
+		if err != nil {
+			closePlayers(players)
+			return nil, err
+		}
+		newGame.Restore(chessGame, record.Remaining())
+	}
+	newGame.OnEvent = func(event sockets.GameEvent, chessGame models.ChessGame) {
+		switch event {
+		case sockets.GameStarted:
+			gameLobby.Started(record.ID, len(chessGame.MoveHistory), sideName(chessGame.CurrentState.Turn))
+		case sockets.GameMoved:
+			gameLobby.Moved(record.ID, len(chessGame.MoveHistory), sideName(chessGame.CurrentState.Turn))
+		case sockets.GameEnded:
+			gameLobby.Ended(record.ID, len(chessGame.MoveHistory), chessGame.Winner.Description())
+		}
+	}
+	log.Println("Creating new game.")
+	if err := games.Add(newGame); err != nil {
+		closePlayers(players)
+		return nil, err
+	}
+	gameLobby.Created(lobby.Game{
+		ID:          record.ID,
+		Players:     [2]string{record.Players[0].Name, record.Players[1].Name},
+		Rated:       record.Rated,
+		TimeControl: record.TimeControl.String(),
+		Moves:       len(record.Moves),
+		Turn:        sideName(int8(len(record.Moves) % 2)),
+		Created:     record.Created,
+	})
+	games.Run(newGame)
+	return newGame, nil
+}
+
+// returns "white" or "black"
+func sideName(side int8) string {
+	if side == models.White {
+		return "white"
+	}
+	return "black"
+}
+
+func closePlayers(players [2]sockets.Player) {
+	for _, player := range players {
+		if player != nil {
+			player.Close()
+		}
+	}
+}
+
+// continues the games that were still being played when the server stopped, so their players can reconnect
+func restoreGames() {
+	records, err := gameStore.UnfinishedGames()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, record := range records {
+		if _, err := startGame(record); err != nil {
+			log.Printf("Could not restore game %v: %v", record.ID, err)
+			continue
+		}
+		log.Printf("Restored game %v after %d moves", record.ID, len(record.Moves))
+	}
+}
+
+// returns the PGN of a stored game
+func recordPGN(record storage.GameRecord) (string, error) {
+	chessGame, err := record.ChessGame()
+	if err != nil {
+		return "", err
+	}
+	names := [2]string{record.Players[0].Name, record.Players[1].Name}
+	return chessGame.PGN(sockets.NewPGNTags(record.Created, names, record.TimeControl, chessGame.Winner))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/lobby"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupLobbyRoutes(app *fiber.App) {
+
+	// seeks, open, live and recently finished games, e.g. /games?status=playing&player=alice&rated=true&timeControl=5%2B3&offset=20&limit=20
+	// seeks are players still waiting in the matchmaking queue, who have no game yet, and are listed in full on every page
+	// open games are those created and waiting for their players to join
+	app.Get("/games", func(c *fiber.Ctx) error {
+		filter := lobby.Filter{
+			Player:      c.Query("player"),
+			TimeControl: c.Query("timeControl"),
+		}
+		if c.Query("status") != "" {
+			status, ok := lobby.ParseStatus(c.Query("status"))
+			if !ok {
+				log.Println("Invalid status")
+				return c.Status(404).SendString("Invalid status, must be seeking, open, playing or finished.")
+			}
+			filter.Status = status
+		}
+		switch rated := c.Query("rated"); rated {
+		case "", "true", "false":
+			filter.Rated = rated
+		default:
+			log.Println("Invalid rated filter")
+			return c.Status(404).SendString("Invalid rated, must be true or false.")
+		}
+		offset, err := strconv.Atoi(c.Query("offset", "0"))
+		if err != nil {
+			log.Println("Invalid offset")
+			return c.Status(404).SendString("Invalid offset.")
+		}
+		limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(lobby.DefaultPageSize)))
+		if err != nil {
+			log.Println("Invalid limit")
+			return c.Status(404).SendString("Invalid limit.")
+		}
+		return c.JSON(gameLobby.List(filter, offset, limit))
+	})
+
+	app.Use("/lobby", func(c *fiber.Ctx) error {
+		if websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+		return fiber.ErrUpgradeRequired
+	})
+
+	// pushes an event as each game is created, starts and ends, and as players join and leave the matchmaking queue
+	app.Get("/lobby", websocket.New(func(conn *websocket.Conn) {
+		events, unsubscribe := gameLobby.Subscribe()
+		defer unsubscribe()
+
+		// nothing is read from the feed, but reading notices when the client goes away
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case event := <-events:
+				if err := conn.WriteJSON(event); err != nil {
+					log.Println(err)
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}))
+}
@@ -1,26 +1,115 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/BrianJHenry/go-chess/server/pkg/accounts"
+	"github.com/BrianJHenry/go-chess/server/pkg/clock"
+	"github.com/BrianJHenry/go-chess/server/pkg/engine"
+	"github.com/BrianJHenry/go-chess/server/pkg/evaluation"
+	"github.com/BrianJHenry/go-chess/server/pkg/matchmaking"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	"github.com/BrianJHenry/go-chess/server/pkg/opponent"
 	"github.com/BrianJHenry/go-chess/server/pkg/sockets"
+	"github.com/BrianJHenry/go-chess/server/pkg/storage"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	bolt "go.etcd.io/bbolt"
 )
 
-var games = make(map[string]*sockets.Game)
+// external UCI engine to play one player games instead of the built-in engine, configured by flags
+var (
+	uciEnginePath = flag.String("uci-engine", "", "path to a UCI engine binary to use as the computer opponent instead of the built-in engine")
+	uciMoveTime   = flag.Duration("uci-movetime", time.Second, "thinking time per move for the UCI engine")
+)
+
+var databasePath = flag.String("db", "go-chess.db", "path of the database file storing accounts and games")
+
+var reconnectGrace = flag.Duration("reconnect-grace", sockets.DefaultReconnectGrace, "how long a disconnected player's seat is held before they lose the game")
+
+var startTimeout = flag.Duration("start-timeout", sockets.DefaultStartTimeout, "how long a game waits for its players to join before it is aborted")
+
+var matchTimeout = flag.Duration("match-timeout", 2*time.Minute, "how long a player waits in the matchmaking queue before giving up")
+
+// ratings of every player, loaded from the database at startup
+var ratings *matchmaking.Ratings
 
-func UnregisterGame(gameID string) {
-	delete(games, gameID)
+// pairs players for two player games, which are rated
+var matchmaker *matchmaking.Matchmaker
+
+// creates the matchmaker, telling the lobby as players join and leave its queue
+func newMatchmaker() *matchmaking.Matchmaker {
+	queue := matchmaking.New(ratings, createMatchGame)
+	queue.OnSeek = func(event matchmaking.SeekEvent, seek matchmaking.Seek) {
+		switch event {
+		case matchmaking.SeekAdded:
+			gameLobby.SeekAdded(seek)
+		case matchmaking.SeekMatched:
+			gameLobby.SeekMatched(seek)
+		case matchmaking.SeekCancelled:
+			gameLobby.SeekCancelled(seek)
+		}
+	}
+	return queue
+}
+
+// creates the game for two players paired by the matchmaker
+func createMatchGame(white string, black string, timeControl clock.TimeControl) (string, error) {
+	game, err := startGame(storage.GameRecord{
+		TimeControl: timeControl,
+		Rated:       true,
+		Players:     [2]storage.PlayerRecord{{Name: white}, {Name: black}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return game.GameID, nil
+}
+
+// returns a context that is cancelled when the client closes the request's connection
+// fasthttp does not read from a connection while its request is being handled, so a read only returns once the client goes away;
+// the returned function stops watching and must be called before the handler returns
+func connectionContext(c *fiber.Ctx) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := c.Context().Conn()
+	watching := make(chan struct{})
+	go func() {
+		defer close(watching)
+		var b [1]byte
+		conn.Read(b[:])
+		cancel()
+	}()
+	return ctx, func() {
+		cancel()
+		// unblock the read, the server sets its own deadline before reading the next request
+		conn.SetReadDeadline(time.Now())
+		<-watching
+		conn.SetReadDeadline(time.Time{})
+	}
+}
+
+// creates the computer opponent for a one player game: "random" for random moves,
+// otherwise the UCI engine if one is configured or else the built-in engine at the given level
+func newComputerOpponent(bot string, level engine.Level) (opponent.Opponent, error) {
+	if bot == "random" {
+		return opponent.NewRandom(), nil
+	}
+	if *uciEnginePath != "" {
+		return opponent.NewUCIEngine(opponent.UCIConfig{Path: *uciEnginePath, MoveTime: *uciMoveTime})
+	}
+	return opponent.NewEngine(level), nil
 }
 
 func setupRoutes(app *fiber.App) {
 
-	app.Get("/findGame/:numPlayers", func(c *fiber.Ctx) error {
+	app.Get("/findGame/:numPlayers", requireUser, func(c *fiber.Ctx) error {
 		numberOfPlayers, err := strconv.Atoi(c.Params("numPlayers"))
 		if err != nil {
 			log.Println("Invalid Find Game Request")
@@ -30,53 +119,134 @@ func setupRoutes(app *fiber.App) {
 			log.Println("Invalid number of players")
 			return c.Status(404).SendString("Invalid number of players.")
 		}
-		if numberOfPlayers == 2 {
-			for key, element := range games {
-				if element.NumberOfPlayers == 2 && len(element.Clients) < 2 {
-					return c.SendString(key)
-				}
+		// computer difficulty, only used in one player games
+		levelNumber := engine.DefaultLevel
+		if c.Query("level") != "" {
+			levelNumber, err = strconv.Atoi(c.Query("level"))
+			if err == nil {
+				_, err = engine.LevelByNumber(levelNumber)
+			}
+			if err != nil {
+				log.Println("Invalid level")
+				return c.Status(404).SendString(fmt.Sprintf("Invalid level, must be a number between 1 and %d.", len(engine.Levels)))
 			}
 		}
-		var randomKey string = fmt.Sprint(rand.Intn(10000000))
-		for _, ok := games[randomKey]; ok; {
-			randomKey = fmt.Sprint(rand.Intn(10000000))
+		// clock, e.g. ?minutes=5&increment=3&mode=bronstein, untimed without minutes
+		timeControl, err := clock.ParseTimeControl(c.Query("minutes"), c.Query("increment"), c.Query("mode"))
+		if err != nil {
+			log.Println("Invalid time control")
+			return c.Status(404).SendString(err.Error())
+		}
+		// two player games are found by the matchmaker, which waits for an opponent with a close rating
+		if numberOfPlayers == 2 {
+			// leave the queue if the player gives up waiting and disconnects
+			connCtx, stopWatching := connectionContext(c)
+			defer stopWatching()
+			ctx, cancel := context.WithTimeout(connCtx, *matchTimeout)
+			defer cancel()
+			match, err := matchmaker.Find(ctx, c.Locals("username").(string), timeControl)
+			if err == context.DeadlineExceeded {
+				log.Println("No opponent found")
+				return c.Status(404).SendString("No opponent found.")
+			}
+			if err == context.Canceled {
+				log.Println("Player left the matchmaking queue")
+				return nil
+			}
+			if err != nil {
+				log.Println(err)
+				return c.Status(404).SendString(err.Error())
+			}
+			return c.SendString(match.GameID)
 		}
-		newGame := sockets.NewGame(numberOfPlayers, randomKey, func(gameID string) {
-			delete(games, gameID)
-			log.Println("Deleting game")
+		// the computer takes black in one player games
+		game, err := startGame(storage.GameRecord{
+			TimeControl: timeControl,
+			Players: [2]storage.PlayerRecord{
+				{Name: c.Locals("username").(string)},
+				{Computer: true, Level: levelNumber, Bot: strings.Clone(c.Query("bot"))},
+			},
 		})
-		log.Println("Creating new game.")
-		games[randomKey] = newGame
-		go newGame.Start()
-		return c.SendString(randomKey)
+		if err != nil {
+			log.Println(err)
+			return c.Status(500).SendString("Could not start the game.")
+		}
+		return c.SendString(game.GameID)
+	})
+
+	// state of the matchmaking queue
+	app.Get("/matchmaking", func(c *fiber.Ctx) error {
+		return c.JSON(matchmaker.Status())
+	})
+
+	app.Get("/ratings/:player", func(c *fiber.Ctx) error {
+		return c.JSON(ratings.Get(c.Params("player")))
 	})
 
+	app.Get("/game/:id/pgn", func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		var pgn string
+		var err error
+		if game, ok := games.Get(id); ok {
+			pgn, err = game.PGN()
+		} else {
+			// games that have ended are only kept in storage
+			record, found, storageErr := gameStore.Game(id)
+			if storageErr == nil && !found {
+				log.Println("Invalid game ID.")
+				return c.Status(404).SendString("Invalid game ID.")
+			}
+			err = storageErr
+			if err == nil {
+				pgn, err = recordPGN(record)
+			}
+		}
+		if err != nil {
+			log.Println(err)
+			return c.Status(500).SendString(err.Error())
+		}
+		c.Set(fiber.HeaderContentType, "application/x-chess-pgn")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"game-%s.pgn\"", id))
+		return c.SendString(pgn)
+	})
+
+	// static evaluation of a position, e.g. /eval?fen=..., defaulting to the starting position
+	app.Get("/eval", func(c *fiber.Ctx) error {
+		fen := c.Query("fen", models.StartingFEN)
+		state, err := models.NewChessStateFromFEN(fen)
+		if err != nil {
+			log.Println("Invalid FEN")
+			return c.Status(404).SendString(err.Error())
+		}
+		return c.JSON(evaluation.Analyze(state))
+	})
+
+	// only logged in users may join games
 	app.Use("/game", func(c *fiber.Ctx) error {
 		if websocket.IsWebSocketUpgrade(c) {
 			c.Locals("allowed", true)
 			return c.Next()
 		}
 		return fiber.ErrUpgradeRequired
-	})
+	}, requireUser)
 
 	app.Get("/game/:id", websocket.New(func(conn *websocket.Conn) {
 		id := conn.Params("id")
 		log.Println("Requested websocket with ID: ", id)
 
-		if game, ok := games[id]; ok {
-			log.Println("Number of clients currently in game: ", len(game.Clients))
-			if len(game.Clients) < game.NumberOfPlayers {
-				client := &sockets.Client{
-					Conn: conn,
-					Game: game,
-				}
-				log.Print("About to register...")
-				game.Register <- client
+		if game, ok := games.Get(id); ok {
+			client := sockets.NewClient(conn, game)
+			client.Username = conn.Locals("username").(string)
+			client.SeatToken = conn.Query("seat")
+			log.Print("About to register...")
+			// the game decides whether there is a seat, including one held for a reconnecting player,
+			// and anyone without one watches
+			if game.Join(client) {
 				log.Println("Registering client and starting read.")
 				client.Read()
 			} else {
-				log.Printf("Game with ID: %v is full.", id)
-				conn.Conn.WriteMessage(1, []byte(fmt.Sprintf("Game with ID: %v is full.", id)))
+				log.Printf("Game with ID: %v has ended.", id)
+				conn.Conn.WriteMessage(1, []byte(fmt.Sprintf("Game with ID: %v has ended.", id)))
 			}
 		} else {
 			log.Println("Invalid game ID.")
@@ -86,15 +256,39 @@ func setupRoutes(app *fiber.App) {
 }
 
 func main() {
+	flag.Parse()
+
+	db, err := bolt.Open(*databasePath, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	accountStore, err = accounts.NewStore(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	gameStore, err = storage.NewStore(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ratings, err = matchmaking.LoadRatings(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	matchmaker = newMatchmaker()
+	restoreGames()
+
 	app := fiber.New()
 
 	app.Use(cors.New(cors.Config{
-		AllowHeaders:     "Origin,Content-Type,Accept,Content-Length,Accept-Language,Accept-Encoding,Connection,Access-Control-Allow-Origin",
+		AllowHeaders:     "Origin,Content-Type,Accept,Content-Length,Accept-Language,Accept-Encoding,Connection,Access-Control-Allow-Origin,Authorization",
 		AllowOrigins:     "*",
 		AllowCredentials: true,
 		AllowMethods:     "GET,POST,HEAD,PUT,DELETE,PATCH,OPTIONS",
 	}))
 
+	setupAuthRoutes(app)
+	setupLobbyRoutes(app)
 	setupRoutes(app)
 
 	log.Println("Starting app.")
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/accounts"
+	"github.com/BrianJHenry/go-chess/server/pkg/lobby"
+	"github.com/BrianJHenry/go-chess/server/pkg/matchmaking"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	"github.com/BrianJHenry/go-chess/server/pkg/sockets"
+	"github.com/BrianJHenry/go-chess/server/pkg/storage"
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+// starts the server on a free port with a fresh database, returning its address
+func startTestServer(t *testing.T) string {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if accountStore, err = accounts.NewStore(db); err != nil {
+		t.Fatal(err)
+	}
+	if gameStore, err = storage.NewStore(db); err != nil {
+		t.Fatal(err)
+	}
+	if ratings, err = matchmaking.LoadRatings(db); err != nil {
+		t.Fatal(err)
+	}
+	matchmaker = newMatchmaker()
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	setupAuthRoutes(app)
+	setupLobbyRoutes(app)
+	setupRoutes(app)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() { app.Shutdown() })
+	return listener.Addr().String()
+}
+
+// registers and logs in a user, returning their session token
+func testLogin(t *testing.T, username string) string {
+	t.Helper()
+	if _, err := accountStore.Register(username, "password123"); err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := accountStore.Login(username, "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func findGame(addr string, token string, query string) (string, error) {
+	request, err := http.NewRequest("GET", fmt.Sprintf("http://%v/findGame/%v", addr, query), nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode != 200 {
+		return "", fmt.Errorf("findGame returned %v: %s", response.StatusCode, body)
+	}
+	return string(body), nil
+}
+
+// connects to a game at the same time as the other connections, returning the type of the first message,
+// which is a seat token for the one that is seated, spectators are sent the game state or the spectator count
+func joinGame(addr string, gameID string, token string, ready *sync.WaitGroup) (int, *websocket.Conn, error) {
+	ready.Done()
+	ready.Wait()
+	conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%v/game/%v?token=%v", addr, gameID, token), nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	var message sockets.Message
+	_, data, err := conn.ReadMessage()
+	if err == nil {
+		err = json.Unmarshal(data, &message)
+	}
+	return message.MessageType, conn, err
+}
+
+func TestConcurrentFindGame(t *testing.T) {
+	addr := startTestServer(t)
+	token := testLogin(t, "alice")
+
+	const requests = 20
+	ids := make(chan string, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gameID, err := findGame(addr, token, "1?bot=random")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids <- gameID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool)
+	for gameID := range ids {
+		if seen[gameID] {
+			t.Errorf("game ID %v was given out twice", gameID)
+		}
+		seen[gameID] = true
+		if _, ok := games.Get(gameID); !ok {
+			t.Errorf("game %v is not registered", gameID)
+		}
+	}
+}
+
+func TestConcurrentJoinTakesOneSeat(t *testing.T) {
+	addr := startTestServer(t)
+	tokens := []string{testLogin(t, "bobby"), testLogin(t, "carol")}
+
+	// bobby and carol are matched into a two player game
+	gameIDs := make([]string, len(tokens))
+	var wg sync.WaitGroup
+	for i, token := range tokens {
+		wg.Add(1)
+		go func(i int, token string) {
+			defer wg.Done()
+			gameID, err := findGame(addr, token, "2")
+			if err != nil {
+				t.Error(err)
+			}
+			gameIDs[i] = gameID
+		}(i, token)
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+	if gameIDs[0] != gameIDs[1] {
+		t.Fatalf("players were matched into different games %v and %v", gameIDs[0], gameIDs[1])
+	}
+
+	// each of them connects several times at once, but only one connection per player gets their seat
+	const connections = 5
+	var ready sync.WaitGroup
+	ready.Add(connections * len(tokens))
+	type joined struct {
+		player      int
+		messageType int
+	}
+	results := make(chan joined, connections*len(tokens))
+	for player, token := range tokens {
+		for i := 0; i < connections; i++ {
+			wg.Add(1)
+			go func(player int, token string) {
+				defer wg.Done()
+				messageType, conn, err := joinGame(addr, gameIDs[0], token, &ready)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				t.Cleanup(func() { conn.Close() })
+				results <- joined{player, messageType}
+			}(player, token)
+		}
+	}
+	wg.Wait()
+	close(results)
+
+	seated := make([]int, len(tokens))
+	for result := range results {
+		if result.messageType == sockets.SeatTokenMessage {
+			seated[result.player]++
+		}
+	}
+	for player, count := range seated {
+		if count != 1 {
+			t.Errorf("player %v was seated %d times, want once", player, count)
+		}
+	}
+}
+
+func TestUnstartedGameIsAborted(t *testing.T) {
+	addr := startTestServer(t)
+	token := testLogin(t, "dave")
+	defer func(timeout time.Duration) { *startTimeout = timeout }(*startTimeout)
+	*startTimeout = 50 * time.Millisecond
+
+	// nobody joins the game created for dave
+	gameID, err := findGame(addr, token, "1?bot=random")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, ok := games.Get(gameID); !ok {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("unstarted game was never removed")
+		}
+	}
+	record, found, err := gameStore.Game(gameID)
+	if err != nil || !found {
+		t.Fatalf("stored game: %v, %v", found, err)
+	}
+	if record.Winner != models.Aborted {
+		t.Errorf("stored result is %q, want %q", record.Winner, models.Aborted)
+	}
+}
+
+func TestRestoredGameInProgressWaitsForReconnect(t *testing.T) {
+	addr := startTestServer(t)
+	token := testLogin(t, "gina")
+	defer func(timeout, grace time.Duration) { *startTimeout, *reconnectGrace = timeout, grace }(*startTimeout, *reconnectGrace)
+	*startTimeout = 50 * time.Millisecond
+	*reconnectGrace = time.Second
+
+	// gina and hank had played two moves when the server stopped
+	record := storage.GameRecord{ID: "restored", Created: time.Now(), Players: [2]storage.PlayerRecord{{Name: "gina"}, {Name: "hank"}}}
+	if err := gameStore.CreateGame(record); err != nil {
+		t.Fatal(err)
+	}
+	chessGame := models.NewChessGame()
+	for _, uci := range []string{"e2e4", "e7e5"} {
+		move, err := chessGame.CurrentState.ParseUCI(uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chessGame.ExecuteMoveOnGame(move)
+		if err := gameStore.RecordMove(record.ID, move, [2]time.Duration{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	restoreGames()
+
+	// the start timeout only applies to games that have not begun
+	time.Sleep(4 * *startTimeout)
+	game, ok := games.Get(record.ID)
+	if !ok {
+		t.Fatal("restored game in progress was aborted")
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%v/game/%v?token=%v", addr, record.ID, token), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if message := readMessage(t, conn); message.MessageType != sockets.SeatTokenMessage {
+		t.Fatalf("gina got message type %d, want a seat token", message.MessageType)
+	}
+	if message := readMessage(t, conn); message.MessageType != sockets.UpdateStateMessage || len(message.GameState.PreviousMoves) != 2 {
+		t.Fatalf("gina got %+v, want the game state after two moves", message)
+	}
+
+	// hank does not come back in time, so gina wins
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, ok := games.Get(record.ID); !ok {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("game did not end after the reconnect grace period")
+		}
+	}
+	if winner := game.ChessGame().Winner; winner != models.WhiteWins {
+		t.Errorf("winner = %q, want %q", winner, models.WhiteWins)
+	}
+}
+
+// reads the next message from a game connection
+func readMessage(t *testing.T, conn *websocket.Conn) sockets.Message {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	var message sockets.Message
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestSpectatorsOnlyChangeTheCount(t *testing.T) {
+	addr := startTestServer(t)
+	playerToken, spectatorToken := testLogin(t, "erin"), testLogin(t, "frank")
+	gameID, err := findGame(addr, playerToken, "1?bot=random")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dial := func(token string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%v/game/%v?token=%v", addr, gameID, token), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+	player := dial(playerToken)
+	defer player.Close()
+	// erin plays white against the computer, so the game starts and waits for her first move
+	for _, want := range []int{sockets.SeatTokenMessage, sockets.UpdateStateMessage} {
+		if message := readMessage(t, player); message.MessageType != want {
+			t.Fatalf("player got message type %d, want %d", message.MessageType, want)
+		}
+	}
+
+	spectator := dial(spectatorToken)
+	if message := readMessage(t, spectator); message.MessageType != sockets.UpdateStateMessage || message.GameState.Spectators != 1 {
+		t.Errorf("spectator first got %+v, want the game state", message)
+	}
+	for _, want := range []string{"1", "0"} {
+		if want == "0" {
+			spectator.Close()
+		}
+		message := readMessage(t, player)
+		if message.MessageType != sockets.SpectatorsMessage || message.MessageContent != want {
+			t.Errorf("player got message type %d %q, want the spectator count %v", message.MessageType, message.MessageContent, want)
+		}
+	}
+}
+
+func TestDisconnectLeavesMatchmakingQueue(t *testing.T) {
+	addr := startTestServer(t)
+	token := testLogin(t, "bobby")
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /findGame/2 HTTP/1.1\r\nHost: %v\r\nAuthorization: Bearer %v\r\n\r\n", addr, token)
+
+	// waits for the queue to hold the number of players, failing the test if it never does
+	waitForQueue := func(waiting int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for matchmaker.Status().Waiting != waiting {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d players waiting, want %d", matchmaker.Status().Waiting, waiting)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitForQueue(1)
+	conn.Close()
+	waitForQueue(0)
+}
+
+func TestLobbyListsSeeks(t *testing.T) {
+	addr := startTestServer(t)
+	token := testLogin(t, "carol")
+
+	feed, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%v/lobby", addr), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer feed.Close()
+	// waits for the next event about a seek, failing the test if it is not of the type
+	nextSeekEvent := func(eventType lobby.EventType) matchmaking.Seek {
+		t.Helper()
+		feed.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for {
+			var event lobby.Event
+			if err := feed.ReadJSON(&event); err != nil {
+				t.Fatal(err)
+			}
+			if event.Seek == nil {
+				continue
+			}
+			if event.Type != eventType {
+				t.Fatalf("got a %v event, want %v", event.Type, eventType)
+			}
+			return *event.Seek
+		}
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /findGame/2?minutes=5&increment=3 HTTP/1.1\r\nHost: %v\r\nAuthorization: Bearer %v\r\n\r\n", addr, token)
+	if seek := nextSeekEvent(lobby.SeekAdded); seek.Player != "carol" || seek.TimeControl != "5+3" {
+		t.Errorf("added seek = %+v", seek)
+	}
+
+	response, err := http.Get(fmt.Sprintf("http://%v/games?status=seeking", addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	var page lobby.Page
+	if err := json.NewDecoder(response.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Seeks) != 1 || page.Seeks[0].Player != "carol" || page.Seeks[0].Rating != matchmaking.DefaultRating || page.Seeks[0].Queued.IsZero() {
+		t.Errorf("listed seeks = %+v, want carol's", page.Seeks)
+	}
+
+	conn.Close()
+	if seek := nextSeekEvent(lobby.SeekCancelled); seek.Player != "carol" {
+		t.Errorf("cancelled seek = %+v", seek)
+	}
+}
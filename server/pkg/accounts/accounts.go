@@ -0,0 +1,225 @@
+package accounts
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// how long a session lasts after logging in
+	SessionLifetime = 30 * 24 * time.Hour
+
+	MinPasswordLength = 8
+	// bcrypt ignores anything past 72 bytes
+	MaxPasswordLength = 72
+)
+
+var (
+	usersBucket    = []byte("users")
+	sessionsBucket = []byte("sessions")
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+
+var (
+	ErrInvalidUsername    = errors.New("usernames must be 3 to 20 letters, digits, underscores or dashes")
+	ErrInvalidPassword    = errors.New("passwords must be 8 to 72 characters")
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidSession     = errors.New("invalid or expired session")
+)
+
+type User struct {
+	Username     string    `json:"username"`
+	PasswordHash []byte    `json:"-"`
+	Created      time.Time `json:"created"`
+}
+
+// users are stored with their password hash, which is left out of the API's JSON
+type storedUser struct {
+	Username     string    `json:"username"`
+	PasswordHash []byte    `json:"passwordHash"`
+	Created      time.Time `json:"created"`
+}
+
+type session struct {
+	Username string    `json:"username"`
+	Expires  time.Time `json:"expires"`
+}
+
+// user accounts and login sessions kept in a bbolt database, safe for concurrent use
+type Store struct {
+	db *bolt.DB
+
+	// bcrypt cost, lowered in tests
+	cost int
+	// returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// opens the account store in an existing database, creating its buckets if needed
+func NewStore(db *bolt.DB) (*Store, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{usersBucket, sessionsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db, cost: bcrypt.DefaultCost, now: time.Now}, nil
+}
+
+// creates an account, usernames are unique ignoring case
+func (store *Store) Register(username string, password string) (User, error) {
+	if !usernamePattern.MatchString(username) {
+		return User{}, ErrInvalidUsername
+	}
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return User{}, ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), store.cost)
+	if err != nil {
+		return User{}, err
+	}
+	user := User{Username: username, PasswordHash: hash, Created: store.now()}
+
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		users := tx.Bucket(usersBucket)
+		key := userKey(username)
+		if users.Get(key) != nil {
+			return ErrUsernameTaken
+		}
+		data, err := json.Marshal(storedUser(user))
+		if err != nil {
+			return err
+		}
+		return users.Put(key, data)
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// returns the user with the username, ignoring case
+func (store *Store) User(username string) (User, bool) {
+	var user User
+	var found bool
+	store.db.View(func(tx *bolt.Tx) error {
+		user, found = getUser(tx, username)
+		return nil
+	})
+	return user, found
+}
+
+// checks the password and starts a session, returning its token
+func (store *Store) Login(username string, password string) (string, User, error) {
+	user, found := store.User(username)
+	if !found {
+		// spend as long as a real check so the response time does not reveal which usernames exist
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return "", User{}, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)) != nil {
+		return "", User{}, ErrInvalidCredentials
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", User{}, err
+	}
+	token := hex.EncodeToString(tokenBytes)
+	data, err := json.Marshal(session{Username: user.Username, Expires: store.now().Add(SessionLifetime)})
+	if err != nil {
+		return "", User{}, err
+	}
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Put(sessionKey(token), data)
+	})
+	if err != nil {
+		return "", User{}, err
+	}
+	return token, user, nil
+}
+
+// returns the user a session token belongs to, expired sessions are removed
+func (store *Store) Authenticate(token string) (User, error) {
+	if token == "" {
+		return User{}, ErrInvalidSession
+	}
+	var user User
+	var valid, expired bool
+	err := store.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(sessionsBucket).Get(sessionKey(token))
+		if data == nil {
+			return nil
+		}
+		var s session
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if !store.now().Before(s.Expires) {
+			expired = true
+			return nil
+		}
+		user, valid = getUser(tx, s.Username)
+		return nil
+	})
+	if err != nil {
+		return User{}, err
+	}
+	if expired {
+		if err := store.Logout(token); err != nil {
+			return User{}, err
+		}
+	}
+	if !valid {
+		return User{}, ErrInvalidSession
+	}
+	return user, nil
+}
+
+// ends a session, ending one that does not exist is not an error
+func (store *Store) Logout(token string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete(sessionKey(token))
+	})
+}
+
+func getUser(tx *bolt.Tx, username string) (User, bool) {
+	data := tx.Bucket(usersBucket).Get(userKey(username))
+	if data == nil {
+		return User{}, false
+	}
+	var stored storedUser
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return User{}, false
+	}
+	return User(stored), true
+}
+
+func userKey(username string) []byte {
+	return []byte(strings.ToLower(username))
+}
+
+// sessions are stored by the hash of their token, so a copy of the database cannot be used to log in
+func sessionKey(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// compared against when the user does not exist
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
@@ -0,0 +1,103 @@
+package accounts
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// a store in a fresh database with cheap password hashing and a clock the test controls
+func newTestStore(t *testing.T) (*Store, func(time.Duration)) {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.cost = bcrypt.MinCost
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestRegister(t *testing.T) {
+	store, _ := newTestStore(t)
+	user, err := store.Register("Alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "Alice" || string(user.PasswordHash) == "correct horse" {
+		t.Errorf("registered user = %+v", user)
+	}
+	if _, err := store.Register("alice", "another password"); err != ErrUsernameTaken {
+		t.Errorf("registering a taken username ignoring case returned %v, want ErrUsernameTaken", err)
+	}
+	for _, test := range []struct {
+		username, password string
+		want               error
+	}{
+		{"al", "correct horse", ErrInvalidUsername},
+		{"bob smith", "correct horse", ErrInvalidUsername},
+		{"bob", "short", ErrInvalidPassword},
+	} {
+		if _, err := store.Register(test.username, test.password); err != test.want {
+			t.Errorf("Register(%q, %q) returned %v, want %v", test.username, test.password, err, test.want)
+		}
+	}
+	if found, ok := store.User("ALICE"); !ok || found.Username != "Alice" {
+		t.Errorf("User(\"ALICE\") = %+v, %v", found, ok)
+	}
+}
+
+func TestLoginAndAuthenticate(t *testing.T) {
+	store, advance := newTestStore(t)
+	if _, err := store.Register("alice", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Login("alice", "wrong horse"); err != ErrInvalidCredentials {
+		t.Errorf("login with the wrong password returned %v, want ErrInvalidCredentials", err)
+	}
+	if _, _, err := store.Login("nobody", "correct horse"); err != ErrInvalidCredentials {
+		t.Errorf("login as an unknown user returned %v, want ErrInvalidCredentials", err)
+	}
+
+	token, user, err := store.Login("ALICE", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "alice" {
+		t.Errorf("logged in as %q, want \"alice\"", user.Username)
+	}
+	authenticated, err := store.Authenticate(token)
+	if err != nil || authenticated.Username != "alice" {
+		t.Errorf("Authenticate = %+v, %v", authenticated, err)
+	}
+	if _, err := store.Authenticate(token + "0"); err != ErrInvalidSession {
+		t.Errorf("authenticating a wrong token returned %v, want ErrInvalidSession", err)
+	}
+
+	// sessions expire
+	advance(SessionLifetime)
+	if _, err := store.Authenticate(token); err != ErrInvalidSession {
+		t.Errorf("authenticating an expired session returned %v, want ErrInvalidSession", err)
+	}
+
+	// and can be ended
+	token, _, err = store.Login("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Logout(token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Authenticate(token); err != ErrInvalidSession {
+		t.Errorf("authenticating after logout returned %v, want ErrInvalidSession", err)
+	}
+}
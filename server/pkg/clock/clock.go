@@ -0,0 +1,246 @@
+package clock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how time is added back to a player's clock
+type Mode int
+
+const (
+	// no time is added, the game is lost when the initial time runs out
+	SuddenDeath Mode = iota
+	// the increment is added after every move
+	Fischer
+	// after every move the time used is added back, up to the increment
+	Bronstein
+	// the clock only starts counting down once the increment has passed on each move
+	SimpleDelay
+)
+
+var modeNames = [4]string{"suddendeath", "fischer", "bronstein", "delay"}
+
+func (mode Mode) String() string {
+	if mode < 0 || int(mode) >= len(modeNames) {
+		return fmt.Sprintf("Mode(%d)", int(mode))
+	}
+	return modeNames[mode]
+}
+
+// returns the mode with the given name, as used in query parameters
+func ParseMode(name string) (Mode, error) {
+	for mode, modeName := range modeNames {
+		if strings.EqualFold(name, modeName) {
+			return Mode(mode), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown time control mode %q", name)
+}
+
+// the time each player starts with and how time is added during the game
+// the zero value is an untimed game
+type TimeControl struct {
+	Initial time.Duration
+	// the increment for Fischer or the delay for Bronstein and simple delay, ignored for sudden death
+	Increment time.Duration
+	Mode      Mode
+}
+
+// returns true if the game has no clock
+func (control TimeControl) Untimed() bool {
+	return control.Initial <= 0
+}
+
+// returns the time control in minutes and seconds, e.g. "5+3", "10 bronstein 5", "3" or "-" if untimed
+func (control TimeControl) String() string {
+	if control.Untimed() {
+		return "-"
+	}
+	initial := strconv.FormatFloat(control.Initial.Minutes(), 'f', -1, 64)
+	increment := strconv.FormatFloat(control.Increment.Seconds(), 'f', -1, 64)
+	switch control.Mode {
+	case Fischer:
+		return initial + "+" + increment
+	case Bronstein, SimpleDelay:
+		return initial + " " + control.Mode.String() + " " + increment
+	default:
+		return initial
+	}
+}
+
+// returns the time control in the format of the PGN TimeControl tag, e.g. "300+3"
+// delays cannot be written in PGN, so those games only show their initial time
+func (control TimeControl) PGNTag() string {
+	if control.Untimed() {
+		return "-"
+	}
+	initial := strconv.FormatFloat(control.Initial.Seconds(), 'f', -1, 64)
+	if control.Mode == Fischer {
+		return initial + "+" + strconv.FormatFloat(control.Increment.Seconds(), 'f', -1, 64)
+	}
+	return initial
+}
+
+// parses a time control from its initial time in minutes, increment in seconds and mode name
+// an empty initial time is an untimed game, an empty mode is Fischer if there is an increment and otherwise sudden death
+func ParseTimeControl(minutes string, seconds string, mode string) (TimeControl, error) {
+	var control TimeControl
+	if minutes == "" {
+		return control, nil
+	}
+	initial, err := strconv.ParseFloat(minutes, 64)
+	if err != nil || initial <= 0 || initial > 24*60 {
+		return control, fmt.Errorf("invalid initial time %q, must be a number of minutes", minutes)
+	}
+	increment := 0.0
+	if seconds != "" {
+		increment, err = strconv.ParseFloat(seconds, 64)
+		if err != nil || increment < 0 || increment > 60*60 {
+			return control, fmt.Errorf("invalid increment %q, must be a number of seconds", seconds)
+		}
+	}
+	control.Initial = time.Duration(initial * float64(time.Minute))
+	control.Increment = time.Duration(increment * float64(time.Second))
+
+	switch {
+	case mode != "":
+		control.Mode, err = ParseMode(mode)
+		if err != nil {
+			return TimeControl{}, err
+		}
+	case increment > 0:
+		control.Mode = Fischer
+	default:
+		control.Mode = SuddenDeath
+	}
+	if control.Mode == SuddenDeath {
+		control.Increment = 0
+	}
+	return control, nil
+}
+
+// a chess clock for two sides, 0 and 1 as the colors are numbered in models
+// only one side's time runs at a time, it is not safe for concurrent use
+type Clock struct {
+	control   TimeControl
+	remaining [2]time.Duration
+	// side whose time is running, -1 when stopped
+	running   int
+	startedAt time.Time
+
+	// returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// creates a stopped clock with the initial time on both sides
+func New(control TimeControl) *Clock {
+	return &Clock{
+		control:   control,
+		remaining: [2]time.Duration{control.Initial, control.Initial},
+		running:   -1,
+		now:       time.Now,
+	}
+}
+
+func (clock *Clock) TimeControl() TimeControl {
+	return clock.control
+}
+
+// sets the time a side has left, e.g. when restoring a saved game
+// it should only be used while the side's time is not running
+func (clock *Clock) SetRemaining(side int, remaining time.Duration) {
+	clock.remaining[side] = remaining
+}
+
+// returns the side whose time is running, -1 if the clock is stopped
+func (clock *Clock) Running() int {
+	return clock.running
+}
+
+// starts the side's time, stopping the other side's
+func (clock *Clock) Start(side int) {
+	clock.Stop()
+	clock.running = side
+	clock.startedAt = clock.now()
+}
+
+// stops the running side's time, keeping what it used so far
+func (clock *Clock) Stop() {
+	if clock.running < 0 {
+		return
+	}
+	clock.remaining[clock.running] = clock.Remaining(clock.running)
+	clock.running = -1
+}
+
+// ends the running side's move: adds its increment and starts the other side's time
+// returns false, leaving the clock stopped, if the running side had already run out of time
+func (clock *Clock) Press() bool {
+	side := clock.running
+	if side < 0 {
+		return true
+	}
+	if clock.TimeToFlag() <= 0 {
+		clock.remaining[side] = 0
+		clock.running = -1
+		return false
+	}
+
+	elapsed := clock.now().Sub(clock.startedAt)
+	remaining := clock.remaining[side] - elapsed
+	switch clock.control.Mode {
+	case Fischer:
+		remaining += clock.control.Increment
+	case Bronstein, SimpleDelay:
+		remaining += minDuration(elapsed, clock.control.Increment)
+	}
+	clock.remaining[side] = remaining
+	clock.running = -1
+	clock.Start(1 - side)
+	return true
+}
+
+// returns the time the side has left, counting down while its time is running
+// during a simple delay the time shown does not change until the delay has passed
+func (clock *Clock) Remaining(side int) time.Duration {
+	remaining := clock.remaining[side]
+	if side != clock.running {
+		return remaining
+	}
+	used := clock.now().Sub(clock.startedAt)
+	if clock.control.Mode == SimpleDelay {
+		used = maxDuration(used-clock.control.Increment, 0)
+	}
+	return maxDuration(remaining-used, 0)
+}
+
+// returns how long until the running side runs out of time, including any simple delay still to come
+// with Bronstein the time given back only arrives after the move, so it does not count here
+// it is zero or negative once the side has run out, and zero if the clock is stopped
+func (clock *Clock) TimeToFlag() time.Duration {
+	if clock.running < 0 {
+		return 0
+	}
+	allowed := clock.remaining[clock.running]
+	if clock.control.Mode == SimpleDelay {
+		allowed += clock.control.Increment
+	}
+	return allowed - clock.now().Sub(clock.startedAt)
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,147 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// a clock whose time only moves when the test advances it
+func newTestClock(control TimeControl) (*Clock, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := New(control)
+	clock.now = func() time.Time { return now }
+	return clock, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestModes(t *testing.T) {
+	tests := []struct {
+		mode Mode
+		// white's time after a 2s move and then after a 10s move, starting from 1 minute with 5s added
+		afterShort, afterLong time.Duration
+		// white's time shown 2s into a move
+		during time.Duration
+	}{
+		{SuddenDeath, 58 * time.Second, 48 * time.Second, 58 * time.Second},
+		{Fischer, 63 * time.Second, 58 * time.Second, 58 * time.Second},
+		{Bronstein, 60 * time.Second, 55 * time.Second, 58 * time.Second},
+		{SimpleDelay, 60 * time.Second, 55 * time.Second, 60 * time.Second},
+	}
+	for _, test := range tests {
+		control := TimeControl{Initial: time.Minute, Increment: 5 * time.Second, Mode: test.mode}
+		if test.mode == SuddenDeath {
+			control.Increment = 0
+		}
+		clock, advance := newTestClock(control)
+		clock.Start(0)
+		advance(2 * time.Second)
+		if got := clock.Remaining(0); got != test.during {
+			t.Errorf("%v: remaining during move = %v, want %v", test.mode, got, test.during)
+		}
+		if !clock.Press() {
+			t.Fatalf("%v: flagged after 2s", test.mode)
+		}
+		if got := clock.Remaining(0); got != test.afterShort {
+			t.Errorf("%v: remaining after 2s move = %v, want %v", test.mode, got, test.afterShort)
+		}
+		if clock.Running() != 1 {
+			t.Errorf("%v: running side after press = %d, want 1", test.mode, clock.Running())
+		}
+
+		advance(time.Second)
+		clock.Press()
+		advance(10 * time.Second)
+		clock.Press()
+		if got := clock.Remaining(0); got != test.afterLong {
+			t.Errorf("%v: remaining after 10s move = %v, want %v", test.mode, got, test.afterLong)
+		}
+	}
+}
+
+func TestFlag(t *testing.T) {
+	clock, advance := newTestClock(TimeControl{Initial: 10 * time.Second, Mode: SuddenDeath})
+	clock.Start(0)
+	advance(4 * time.Second)
+	if got := clock.TimeToFlag(); got != 6*time.Second {
+		t.Errorf("TimeToFlag() = %v, want 6s", got)
+	}
+	advance(6 * time.Second)
+	if clock.TimeToFlag() > 0 {
+		t.Error("TimeToFlag() > 0 after the time ran out")
+	}
+	if clock.Press() {
+		t.Error("Press() = true after the time ran out")
+	}
+	if clock.Remaining(0) != 0 || clock.Running() != -1 {
+		t.Errorf("after flag fall remaining = %v and running = %d, want 0 and -1", clock.Remaining(0), clock.Running())
+	}
+	if clock.Remaining(1) != 10*time.Second {
+		t.Errorf("opponent's time = %v, want 10s", clock.Remaining(1))
+	}
+}
+
+func TestDelayCountsTowardsFlag(t *testing.T) {
+	control := TimeControl{Initial: 10 * time.Second, Increment: 3 * time.Second}
+	for _, test := range []struct {
+		mode Mode
+		want time.Duration
+	}{
+		{SimpleDelay, 13 * time.Second},
+		{Bronstein, 10 * time.Second},
+	} {
+		control.Mode = test.mode
+		clock, _ := newTestClock(control)
+		clock.Start(1)
+		if got := clock.TimeToFlag(); got != test.want {
+			t.Errorf("%v: TimeToFlag() = %v, want %v", test.mode, got, test.want)
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	clock, advance := newTestClock(TimeControl{Initial: time.Minute, Increment: time.Second, Mode: Fischer})
+	clock.Start(0)
+	advance(5 * time.Second)
+	clock.Stop()
+	advance(time.Hour)
+	if got := clock.Remaining(0); got != 55*time.Second {
+		t.Errorf("remaining after stop = %v, want 55s", got)
+	}
+	if !clock.Press() || clock.Running() != -1 {
+		t.Error("pressing a stopped clock should do nothing")
+	}
+}
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		minutes, seconds, mode string
+		want                   TimeControl
+		text, pgn              string
+	}{
+		{"", "", "", TimeControl{}, "-", "-"},
+		{"5", "", "", TimeControl{Initial: 5 * time.Minute, Mode: SuddenDeath}, "5", "300"},
+		{"3", "2", "", TimeControl{Initial: 3 * time.Minute, Increment: 2 * time.Second, Mode: Fischer}, "3+2", "180+2"},
+		{"0.5", "0", "", TimeControl{Initial: 30 * time.Second, Mode: SuddenDeath}, "0.5", "30"},
+		{"10", "5", "bronstein", TimeControl{Initial: 10 * time.Minute, Increment: 5 * time.Second, Mode: Bronstein}, "10 bronstein 5", "600"},
+		{"10", "5", "Delay", TimeControl{Initial: 10 * time.Minute, Increment: 5 * time.Second, Mode: SimpleDelay}, "10 delay 5", "600"},
+		{"10", "5", "suddendeath", TimeControl{Initial: 10 * time.Minute, Mode: SuddenDeath}, "10", "600"},
+	}
+	for _, test := range tests {
+		control, err := ParseTimeControl(test.minutes, test.seconds, test.mode)
+		if err != nil {
+			t.Errorf("ParseTimeControl(%q, %q, %q): %v", test.minutes, test.seconds, test.mode, err)
+			continue
+		}
+		if control != test.want {
+			t.Errorf("ParseTimeControl(%q, %q, %q) = %+v, want %+v", test.minutes, test.seconds, test.mode, control, test.want)
+		}
+		if control.String() != test.text || control.PGNTag() != test.pgn {
+			t.Errorf("%+v formats as %q and %q, want %q and %q", control, control.String(), control.PGNTag(), test.text, test.pgn)
+		}
+	}
+
+	for _, bad := range [][3]string{{"x", "", ""}, {"0", "", ""}, {"-1", "", ""}, {"5", "-2", ""}, {"5", "y", ""}, {"5", "2", "hourglass"}} {
+		if _, err := ParseTimeControl(bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("ParseTimeControl(%q, %q, %q) succeeded, want error", bad[0], bad[1], bad[2])
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+// playing strength of the computer opponent
+type Level struct {
+	// search bounds, Limits.Margin is also how far below the best move a move may score and still be chosen
+	Limits Limits
+	// probability of deliberately playing a random move instead of one of the best
+	BlunderChance float64
+}
+
+// difficulty levels from weakest to strongest, level n is Levels[n-1]
+var Levels = []Level{
+	{Limits: Limits{Depth: 1, Nodes: 5000, MoveTime: time.Second, Margin: 150}, BlunderChance: 0.3},
+	{Limits: Limits{Depth: 2, Nodes: 10000, MoveTime: time.Second, Margin: 100}, BlunderChance: 0.2},
+	{Limits: Limits{Depth: 2, Nodes: 20000, MoveTime: time.Second, Margin: 60}, BlunderChance: 0.1},
+	{Limits: Limits{Depth: 3, Nodes: 50000, MoveTime: time.Second, Margin: 40}, BlunderChance: 0.05},
+	{Limits: Limits{Depth: 4, Nodes: 100000, MoveTime: time.Second, Margin: 20}, BlunderChance: 0.02},
+	{Limits: Limits{Depth: 5, MoveTime: time.Second, Margin: 10}},
+	{Limits: Limits{Depth: 6, MoveTime: time.Second}},
+	{Limits: Limits{Depth: 8, MoveTime: time.Second}},
+}
+
+// level used when none is requested, the strongest
+var DefaultLevel = len(Levels)
+
+// returns the settings for a one based level number
+func LevelByNumber(number int) (Level, error) {
+	if number < 1 || number > len(Levels) {
+		return Level{}, fmt.Errorf("invalid level %d: must be between 1 and %d", number, len(Levels))
+	}
+	return Levels[number-1], nil
+}
+
+// searches the position within the level's limits and picks the move to play:
+// usually a random one of the moves scoring within the level's margin of the best,
+// and with the level's blunder chance a random move that does not walk into a forced mate
+// the returned result describes the search, its BestMove is the chosen move
+func (engine *Engine) ChooseMove(state *models.ChessState, history []uint64, level Level, rng *rand.Rand) Result {
+	result := engine.Search(state, history, level.Limits)
+	if len(result.RootMoves) <= 1 {
+		return result
+	}
+
+	var chosen RootMove
+	if rng.Float64() < level.BlunderChance {
+		chosen = engine.chooseBlunder(state, history, level.Limits, result, rng)
+	} else {
+		best := result.RootMoves[0].Score
+		var candidates []RootMove
+		for _, rootMove := range result.RootMoves {
+			// never give away a mate, or play a move that allows one when the best move does not
+			if rootMove.Score == best || (!IsMateScore(best) && !IsMateScore(rootMove.Score) && rootMove.Score > best-level.Limits.Margin) {
+				candidates = append(candidates, rootMove)
+			}
+		}
+		chosen = candidates[rng.Intn(len(candidates))]
+	}
+
+	result.BestMove = chosen.Move
+	result.Score = chosen.Score
+	if len(result.PV) == 0 || result.PV[0] != chosen.Move {
+		result.PV = []models.Move{chosen.Move}
+	}
+	return result
+}
+
+// picks a random root move that does not walk into a forced mate, or the best move if they all do
+// root scores outside the search's margin are only upper bounds, so each move tried is searched again
+// with a full window to the depth of the root search before it is played
+// the searches share whatever the root search left of the limits' node and time budget,
+// once it runs out the best move is played instead
+func (engine *Engine) chooseBlunder(state *models.ChessState, history []uint64, limits Limits, result Result, rng *rand.Rand) RootMove {
+	best := result.RootMoves[0]
+	if best.Score < -mateBound {
+		return result.RootMoves[rng.Intn(len(result.RootMoves))]
+	}
+
+	depth := result.Depth - 1
+	if depth < 1 {
+		depth = 1
+	}
+	nodes := limits.Nodes - result.Nodes
+	if limits.Nodes > 0 && nodes <= 0 {
+		return best
+	}
+	var deadline time.Time
+	if limits.MoveTime > 0 {
+		deadline = time.Now().Add(limits.MoveTime - result.Time)
+	}
+	childHistory := append([]uint64(nil), history...)
+	if len(childHistory) == 0 || childHistory[len(childHistory)-1] != state.Hash() {
+		childHistory = append(childHistory, state.Hash())
+	}
+	searcher := New()
+	searcher.Table = engine.Table
+	for _, i := range rng.Perm(len(result.RootMoves)) {
+		rootMove := result.RootMoves[i]
+		// an upper bound this low already proves the move is mated
+		if rootMove.Score < -mateBound {
+			continue
+		}
+		childLimits := Limits{Depth: depth}
+		if limits.Nodes > 0 {
+			childLimits.Nodes = nodes
+		}
+		if limits.MoveTime > 0 {
+			childLimits.MoveTime = time.Until(deadline)
+			if childLimits.MoveTime <= 0 {
+				return best
+			}
+		}
+		child := state.Copy()
+		child.MakeMove(rootMove.Move)
+		childResult := searcher.Search(child, childHistory, childLimits)
+		// a search cut short by the budget has not looked deep enough to rule out a mate
+		if len(childResult.RootMoves) > 0 && childResult.Depth < depth {
+			return best
+		}
+		if score := -childResult.Score; score >= -mateBound {
+			return RootMove{Move: rootMove.Move, Score: score}
+		}
+		nodes -= childResult.Nodes
+		if limits.Nodes > 0 && nodes <= 0 {
+			return best
+		}
+	}
+	return best
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+func TestRootMovesWithinMarginAreExact(t *testing.T) {
+	state := mustState(t, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	const depth, margin = 2, 80
+	result := New().Search(state, nil, Limits{Depth: depth, Margin: margin})
+	if len(result.RootMoves) != len(state.EnumerateMoves()) {
+		t.Fatalf("got %d root moves, want %d", len(result.RootMoves), len(state.EnumerateMoves()))
+	}
+
+	best := result.RootMoves[0].Score
+	if best != result.Score {
+		t.Errorf("first root move scores %d, result score is %d", best, result.Score)
+	}
+	for _, rootMove := range result.RootMoves {
+		if rootMove.Score <= best-margin {
+			break
+		}
+		child := state.Copy()
+		child.MakeMove(rootMove.Move)
+		exact := -New().Search(child, nil, Limits{Depth: depth - 1}).Score
+		if rootMove.Score != exact {
+			t.Errorf("%s scored %d at the root, %d when searched alone", rootMove.Move.UCI(), rootMove.Score, exact)
+		}
+	}
+}
+
+func TestChooseMoveVariesAmongEqualMoves(t *testing.T) {
+	level := Level{Limits: Limits{Depth: 2, Margin: 30}}
+	rng := rand.New(rand.NewSource(1))
+	chosen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		result := New().ChooseMove(models.NewChessState(), nil, level, rng)
+		chosen[result.BestMove.UCI()] = true
+	}
+	if len(chosen) < 2 {
+		t.Errorf("always chose %v from the starting position", chosen)
+	}
+}
+
+func TestChooseMoveKeepsMate(t *testing.T) {
+	state := mustState(t, "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1")
+	level := Level{Limits: Limits{Depth: 2, Margin: 1000}}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		if move := New().ChooseMove(state, nil, level, rng).BestMove.UCI(); move != "a1a8" {
+			t.Fatalf("chose %s instead of mate", move)
+		}
+	}
+}
+
+func TestBlunderAvoidsMate(t *testing.T) {
+	// every rook move along the a-file except Ra8 allows Re1#
+	state := mustState(t, "4r1k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	level := Level{Limits: Limits{Depth: 2}, BlunderChance: 1}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 30; i++ {
+		result := New().ChooseMove(state, nil, level, rng)
+		child := state.Copy()
+		child.MakeMove(result.BestMove)
+		if score := -New().Search(child, nil, Limits{Depth: 2}).Score; score < -mateBound {
+			t.Fatalf("blundered into mate with %s", result.BestMove.UCI())
+		}
+	}
+}
+
+func TestBlunderStaysWithinLimits(t *testing.T) {
+	state := models.NewChessState()
+	result := New().Search(state, nil, Limits{Depth: 3})
+	rng := rand.New(rand.NewSource(1))
+	// the root search used up the whole budget, so there is nothing left to check a blunder with
+	for _, limits := range []Limits{{Depth: 3, Nodes: result.Nodes}, {Depth: 3, MoveTime: result.Time}} {
+		for i := 0; i < 10; i++ {
+			if move := New().chooseBlunder(state, nil, limits, result, rng); move != result.RootMoves[0] {
+				t.Fatalf("with limits %+v chose %s instead of the best move", limits, move.Move.UCI())
+			}
+		}
+	}
+}
+
+func TestLevelByNumber(t *testing.T) {
+	for _, number := range []int{0, len(Levels) + 1} {
+		if _, err := LevelByNumber(number); err == nil {
+			t.Errorf("level %d accepted", number)
+		}
+	}
+	if level, err := LevelByNumber(DefaultLevel); err != nil || level.BlunderChance != 0 {
+		t.Errorf("default level = %+v, %v", level, err)
+	}
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+// ordering scores, higher is searched first
+const (
+	pvMoveScore   = 1 << 30
+	ttMoveScore   = pvMoveScore - 1
+	captureScore  = 1 << 24
+	killerScore   = 1 << 22
+	maxHistory    = killerScore - 1
+	victimWeight  = 16
+	promotionBase = captureScore
+)
+
+// forgets the killer moves and weakens the history scores left over from the previous search
+func (engine *Engine) resetOrdering() {
+	engine.killers = [MaxDepth][2]models.Move{}
+	engine.halveHistory()
+}
+
+// remembers a quiet move that caused a beta cutoff, as a killer for its ply and in the history table
+func (engine *Engine) recordCutoff(move models.Move, ply, depth int) {
+	if engine.killers[ply][0] != move {
+		engine.killers[ply][1] = engine.killers[ply][0]
+		engine.killers[ply][0] = move
+	}
+
+	entry := &engine.history[engine.state.Turn][squareIndex(move.OldSquare)][squareIndex(move.NewSquare)]
+	*entry += depth * depth
+	if *entry > maxHistory {
+		// keep history below the killers
+		engine.halveHistory()
+	}
+}
+
+func (engine *Engine) halveHistory() {
+	for color := range engine.history {
+		for from := range engine.history[color] {
+			for to := range engine.history[color][from] {
+				engine.history[color][from][to] /= 2
+			}
+		}
+	}
+}
+
+// sorts moves so the most promising are searched first:
+// the principal variation move, the transposition table's best move, then captures by most valuable victim / least valuable attacker,
+// then killer moves and finally quiet moves by their history score
+func (engine *Engine) orderMoves(moves []models.Move, ply int, pvMove, ttMove models.Move) {
+	scores := make([]int, len(moves))
+	for i, move := range moves {
+		scores[i] = engine.scoreMove(move, ply, pvMove, ttMove)
+	}
+
+	// insertion sort, move lists are short
+	for i := 1; i < len(moves); i++ {
+		move, score := moves[i], scores[i]
+		j := i - 1
+		for ; j >= 0 && scores[j] < score; j-- {
+			moves[j+1], scores[j+1] = moves[j], scores[j]
+		}
+		moves[j+1], scores[j+1] = move, score
+	}
+}
+
+func (engine *Engine) scoreMove(move models.Move, ply int, pvMove, ttMove models.Move) int {
+	if move == pvMove {
+		return pvMoveScore
+	}
+	if move == ttMove {
+		return ttMoveScore
+	}
+
+	board := engine.state.Board
+	score := 0
+	if move.Type >= models.PromoteQueen {
+		score += promotionBase + int(promotionKind(move.Type))*victimWeight
+	}
+	if engine.state.IsCapture(move) {
+		victim := int8(models.WhitePawn)
+		if move.Type != models.EnPassant {
+			victim = kind(board[move.NewSquare.Row][move.NewSquare.Col])
+		}
+		attacker := kind(board[move.OldSquare.Row][move.OldSquare.Col])
+		return score + captureScore + int(victim)*victimWeight - int(attacker)
+	}
+	if score > 0 {
+		return score
+	}
+
+	if ply < MaxDepth {
+		if engine.killers[ply][0] == move {
+			return killerScore + 1
+		}
+		if engine.killers[ply][1] == move {
+			return killerScore
+		}
+	}
+	return engine.history[engine.state.Turn][squareIndex(move.OldSquare)][squareIndex(move.NewSquare)]
+}
+
+func squareIndex(location models.Location) int {
+	return location.Row*8 + location.Col
+}
+
+// returns the piece value with the color removed
+func kind(piece int8) int8 {
+	if piece < 0 {
+		return -piece
+	}
+	return piece
+}
+
+// returns the unsigned piece a promotion move creates
+func promotionKind(moveType models.MoveType) int8 {
+	switch moveType {
+	case models.PromoteQueen:
+		return models.WhiteQueen
+	case models.PromoteRook:
+		return models.WhiteRook
+	case models.PromoteBishop:
+		return models.WhiteBishop
+	case models.PromoteKnight:
+		return models.WhiteKnight
+	}
+	return models.EmptySquare
+}
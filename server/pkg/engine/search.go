@@ -0,0 +1,447 @@
+package engine
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/evaluation"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	"github.com/BrianJHenry/go-chess/server/pkg/transposition"
+)
+
+const (
+	// deepest ply the search will reach, including extensions and quiescence
+	MaxDepth = 64
+	// score for delivering checkmate on the current move, mates further away score one less per ply
+	MateScore = 100000
+	infinity  = MateScore + 1
+	// scores beyond this are mates
+	mateBound = MateScore - MaxDepth
+	// how many nodes are searched between checks of the stop flag and the clock
+	checkInterval = 1024
+)
+
+// bounds for a search, a zero field means no limit on that dimension
+// the first iteration always completes so a search always finds a move
+type Limits struct {
+	Depth    int
+	Nodes    int64
+	MoveTime time.Duration
+	// root moves scoring within Margin centipawns of the best move are searched with a window wide enough
+	// for their scores in Result.RootMoves to be exact, 0 only guarantees the best move's score
+	Margin int
+}
+
+// a legal move in the searched position with its score from the last completed iteration
+// scores more than the search's Margin below the best score are upper bounds
+type RootMove struct {
+	Move  models.Move
+	Score int
+}
+
+// outcome of a search
+// Score is in centipawns from the point of view of the side to move
+// BestMove is the zero Move when the position has no legal moves
+type Result struct {
+	BestMove models.Move
+	Score    int
+	Depth    int
+	Nodes    int64
+	Time     time.Duration
+	PV       []models.Move
+	// every legal move, best first
+	RootMoves []RootMove
+}
+
+// returns true if the score means a forced mate was found, for either side
+func IsMateScore(score int) bool {
+	return score > mateBound || score < -mateBound
+}
+
+// iterative deepening alpha-beta searcher
+// an Engine keeps move ordering statistics between searches and must not run two searches at once
+type Engine struct {
+	// called after every completed iteration, e.g. to report progress
+	OnIteration func(Result)
+	// remembers results between searches and between transpositions within one, nil to search without
+	// a table may be shared by engines searching in parallel
+	Table *transposition.Table
+
+	state *models.ChessState
+	// hashes of the game's positions followed by the positions on the current search path
+	path []uint64
+
+	limits   Limits
+	deadline time.Time
+	start    time.Time
+	nodes    int64
+	stopped  atomic.Bool
+	aborted  bool
+	depth    int
+
+	// scores of the root moves searched so far in the current iteration
+	rootMoves []RootMove
+
+	// principal variation of the last completed iteration, searched first in the next one
+	previousPV []models.Move
+	followPV   bool
+
+	killers  [MaxDepth][2]models.Move
+	history  [2][64][64]int
+	pv       [MaxDepth + 1][MaxDepth + 1]models.Move
+	pvLength [MaxDepth + 1]int
+}
+
+func New() *Engine {
+	return &Engine{}
+}
+
+// asks a running search to return as soon as possible, it will return the best move found so far
+// safe to call from another goroutine
+func (engine *Engine) Stop() {
+	engine.stopped.Store(true)
+}
+
+// searches for the best move in a position
+// history holds the hashes of the positions reached earlier in the game (as returned by ChessGame.PositionHistory),
+// so that repetitions are scored as draws; it may be nil
+// the state is not modified
+func (engine *Engine) Search(state *models.ChessState, history []uint64, limits Limits) Result {
+	engine.start = time.Now()
+	engine.state = state.Copy()
+	engine.path = append(engine.path[:0], history...)
+	if len(engine.path) == 0 || engine.path[len(engine.path)-1] != state.Hash() {
+		engine.path = append(engine.path, state.Hash())
+	}
+	engine.limits = limits
+	engine.deadline = time.Time{}
+	if limits.MoveTime > 0 {
+		engine.deadline = engine.start.Add(limits.MoveTime)
+	}
+	engine.nodes = 0
+	engine.aborted = false
+	engine.stopped.Store(false)
+	engine.previousPV = nil
+	engine.resetOrdering()
+	if engine.Table != nil {
+		engine.Table.NewSearch()
+	}
+
+	maxDepth := limits.Depth
+	if maxDepth <= 0 || maxDepth > MaxDepth-1 {
+		maxDepth = MaxDepth - 1
+	}
+
+	result := Result{}
+	moves := engine.state.EnumerateMoves()
+	if len(moves) == 0 {
+		if engine.state.IsInCheck() {
+			result.Score = -MateScore
+		}
+		return result
+	}
+	result.BestMove = moves[0]
+	for _, move := range moves {
+		result.RootMoves = append(result.RootMoves, RootMove{Move: move})
+	}
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		if depth > 1 && (engine.stopped.Load() || (!engine.deadline.IsZero() && time.Now().After(engine.deadline))) {
+			break
+		}
+		engine.depth = depth
+		engine.followPV = true
+		engine.rootMoves = engine.rootMoves[:0]
+		score := engine.search(depth, 0, -infinity, infinity)
+		if engine.aborted {
+			break
+		}
+
+		result.Score = score
+		result.Depth = depth
+		result.PV = append([]models.Move(nil), engine.pv[0][:engine.pvLength[0]]...)
+		engine.previousPV = result.PV
+		result.RootMoves = append([]RootMove(nil), engine.rootMoves...)
+		sort.SliceStable(result.RootMoves, func(i, j int) bool {
+			return result.RootMoves[i].Score > result.RootMoves[j].Score
+		})
+		if len(result.PV) > 0 {
+			result.BestMove = result.PV[0]
+		}
+		result.Nodes = engine.nodes
+		result.Time = time.Since(engine.start)
+		if engine.OnIteration != nil {
+			engine.OnIteration(result)
+		}
+
+		// a forced mate will not get any shorter by searching deeper
+		if IsMateScore(score) && MateScore-abs(score) <= depth {
+			break
+		}
+		// with only one legal move there is nothing to decide
+		if len(moves) == 1 {
+			break
+		}
+	}
+
+	result.Nodes = engine.nodes
+	result.Time = time.Since(engine.start)
+	return result
+}
+
+// negamax alpha-beta search of the current position, returning its score for the side to move
+func (engine *Engine) search(depth, ply int, alpha, beta int) int {
+	engine.pvLength[ply] = ply
+	if engine.shouldStop() {
+		return 0
+	}
+	state := engine.state
+
+	if ply > 0 && engine.isDraw() {
+		return 0
+	}
+	if ply >= MaxDepth-1 {
+		return evaluation.Evaluate(state)
+	}
+
+	inCheck := state.IsInCheck()
+	if inCheck {
+		// search checks one ply further so mating attacks and escapes are not cut off at the horizon
+		depth++
+	}
+	if depth <= 0 {
+		return engine.quiescence(ply, alpha, beta)
+	}
+
+	engine.nodes++
+	var ttMove models.Move
+	if engine.Table != nil {
+		if entry, ok := engine.Table.Probe(state.Hash()); ok {
+			ttMove = entry.Move
+			// the root always searches so that it has a move and root scores to report
+			if ply > 0 && entry.Depth >= depth {
+				score := scoreFromTable(entry.Score, ply)
+				if entry.Bound == transposition.Exact ||
+					(entry.Bound == transposition.Lower && score >= beta) ||
+					(entry.Bound == transposition.Upper && score <= alpha) {
+					return score
+				}
+			}
+		}
+	}
+
+	moves := state.EnumerateMoves()
+	if len(moves) == 0 {
+		if inCheck {
+			return -MateScore + ply
+		}
+		return 0
+	}
+
+	var pvMove models.Move
+	if engine.followPV {
+		if ply < len(engine.previousPV) {
+			pvMove = engine.previousPV[ply]
+		} else {
+			engine.followPV = false
+		}
+	}
+	engine.orderMoves(moves, ply, pvMove, ttMove)
+
+	originalAlpha := alpha
+	best := -infinity
+	var bestMove models.Move
+	for _, move := range moves {
+		quiet := !state.IsCapture(move) && move.Type < models.PromoteQueen
+
+		// only the first child of a node on the previous principal variation can be on it too
+		engine.followPV = engine.followPV && move == pvMove
+
+		// at the root, moves close to the best are searched with a lowered bound so their scores are exact
+		childAlpha := alpha
+		if ply == 0 {
+			childAlpha = max(alpha-engine.limits.Margin, -infinity)
+		}
+
+		undo := state.MakeMove(move)
+		engine.path = append(engine.path, state.Hash())
+		score := -engine.search(depth-1, ply+1, -beta, -childAlpha)
+		engine.path = engine.path[:len(engine.path)-1]
+		state.UnmakeMove(move, undo)
+
+		if engine.aborted {
+			return 0
+		}
+		if ply == 0 {
+			engine.rootMoves = append(engine.rootMoves, RootMove{Move: move, Score: score})
+		}
+		if score > best {
+			best = score
+		}
+		if score > alpha {
+			alpha = score
+			bestMove = move
+			engine.updatePV(ply, move)
+			if score >= beta {
+				if quiet {
+					engine.recordCutoff(move, ply, depth)
+				}
+				break
+			}
+		}
+	}
+
+	if engine.Table != nil {
+		bound := transposition.Exact
+		if best >= beta {
+			bound = transposition.Lower
+		} else if best <= originalAlpha {
+			bound = transposition.Upper
+		}
+		engine.Table.Store(state.Hash(), transposition.Entry{Move: bestMove, Score: scoreToTable(best, ply), Depth: depth, Bound: bound})
+	}
+	return best
+}
+
+// mate scores count plies from the root, in the table they count from the stored position so they stay valid in other searches
+func scoreToTable(score, ply int) int {
+	if score > mateBound {
+		return score + ply
+	}
+	if score < -mateBound {
+		return score - ply
+	}
+	return score
+}
+
+func scoreFromTable(score, ply int) int {
+	if score > mateBound {
+		return score - ply
+	}
+	if score < -mateBound {
+		return score + ply
+	}
+	return score
+}
+
+// searches captures and promotions until the position is quiet, so that the evaluation is not taken in the middle of an exchange
+func (engine *Engine) quiescence(ply, alpha, beta int) int {
+	engine.pvLength[ply] = ply
+	if engine.shouldStop() {
+		return 0
+	}
+	engine.nodes++
+	state := engine.state
+
+	if ply >= MaxDepth-1 {
+		return evaluation.Evaluate(state)
+	}
+
+	// out of check the side to move may decline every capture, in check every evasion has to be searched
+	// stalemates are only found by the main search, quiescence treats them as the stand pat score
+	inCheck := state.IsInCheck()
+	best := -infinity
+	if !inCheck {
+		standPat := evaluation.Evaluate(state)
+		if standPat >= beta {
+			return standPat
+		}
+		if standPat > alpha {
+			alpha = standPat
+		}
+		best = standPat
+	}
+
+	moves := state.EnumerateMoves()
+	if inCheck && len(moves) == 0 {
+		return -MateScore + ply
+	}
+	if !inCheck {
+		tactical := moves[:0]
+		for _, move := range moves {
+			if state.IsCapture(move) || move.Type == models.PromoteQueen {
+				tactical = append(tactical, move)
+			}
+		}
+		moves = tactical
+	}
+	engine.orderMoves(moves, ply, models.Move{}, models.Move{})
+
+	for _, move := range moves {
+		undo := state.MakeMove(move)
+		score := -engine.quiescence(ply+1, -beta, -alpha)
+		state.UnmakeMove(move, undo)
+
+		if engine.aborted {
+			return 0
+		}
+		if score > best {
+			best = score
+		}
+		if score > alpha {
+			alpha = score
+			engine.updatePV(ply, move)
+			if score >= beta {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// returns true if the current position is drawn by the fifty-move rule, insufficient material or a repetition
+// within the search a single repetition counts, as the side that could avoid it would already have done so
+func (engine *Engine) isDraw() bool {
+	state := engine.state
+	if state.HalfmoveClock() >= 100 || state.Board.IsInsufficientMaterial() {
+		return true
+	}
+	// only positions since the last capture or pawn move can repeat, and only with the same side to move
+	last := len(engine.path) - 1
+	for i := last - 2; i >= 0 && i >= last-state.HalfmoveClock(); i -= 2 {
+		if engine.path[i] == engine.path[last] {
+			return true
+		}
+	}
+	return false
+}
+
+// makes the move the start of the principal variation at this ply, followed by the variation found below it
+func (engine *Engine) updatePV(ply int, move models.Move) {
+	engine.pv[ply][ply] = move
+	next := ply + 1
+	copy(engine.pv[ply][next:], engine.pv[next][next:engine.pvLength[next]])
+	engine.pvLength[ply] = engine.pvLength[next]
+}
+
+// checks the stop flag and the search limits every few nodes
+// the first iteration is never interrupted so there is always a move to return
+func (engine *Engine) shouldStop() bool {
+	if engine.aborted {
+		return true
+	}
+	if engine.depth <= 1 || engine.nodes%checkInterval != 0 {
+		return false
+	}
+	if engine.stopped.Load() ||
+		(engine.limits.Nodes > 0 && engine.nodes >= engine.limits.Nodes) ||
+		(!engine.deadline.IsZero() && time.Now().After(engine.deadline)) {
+		engine.aborted = true
+	}
+	return engine.aborted
+}
+
+func max(x, y int) int {
+	if x > y {
+		return x
+	}
+	return y
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
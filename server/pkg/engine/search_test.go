@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	"github.com/BrianJHenry/go-chess/server/pkg/transposition"
+)
+
+func mustState(t *testing.T, fen string) *models.ChessState {
+	t.Helper()
+	state, err := models.NewChessStateFromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+// runs a test with an engine searching without a transposition table and with one
+func forEachTable(t *testing.T, test func(t *testing.T, engine *Engine)) {
+	t.Run("no table", func(t *testing.T) {
+		test(t, New())
+	})
+	t.Run("table", func(t *testing.T) {
+		engine := New()
+		engine.Table = transposition.New(1)
+		test(t, engine)
+	})
+}
+
+func TestSearchFindsMate(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		depth int
+		best  string
+		plies int
+	}{
+		{"back rank", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 3, "a1a8", 1},
+		{"smothered", "6rk/6pp/8/6N1/8/8/8/6K1 w - - 0 1", 3, "g5f7", 1},
+		{"queen and rook ladder", "7k/8/8/8/8/8/R7/1Q4K1 w - - 0 1", 5, "", 3},
+		{"black mates", "r5k1/8/8/8/8/8/5PPP/6K1 b - - 0 1", 3, "a8a1", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			forEachTable(t, func(t *testing.T, engine *Engine) {
+				state := mustState(t, test.fen)
+				result := engine.Search(state, nil, Limits{Depth: test.depth})
+				if result.Score != MateScore-test.plies {
+					t.Errorf("score = %d, want mate in %d plies (%d), pv %v", result.Score, test.plies, MateScore-test.plies, result.PV)
+				}
+				if test.best != "" && result.BestMove.UCI() != test.best {
+					t.Errorf("best move = %s, want %s", result.BestMove.UCI(), test.best)
+				}
+			})
+		})
+	}
+}
+
+func TestSearchWinsMaterial(t *testing.T) {
+	// the knight fork on c7 wins the rook on a8
+	state := mustState(t, "r3k3/8/8/1N6/8/8/8/4K3 w - - 0 1")
+	forEachTable(t, func(t *testing.T, engine *Engine) {
+		result := engine.Search(state, nil, Limits{Depth: 4})
+		if result.BestMove.UCI() != "b5c7" {
+			t.Errorf("best move = %s, want b5c7 (pv %v)", result.BestMove.UCI(), result.PV)
+		}
+	})
+}
+
+func TestTableSavesWork(t *testing.T) {
+	state := mustState(t, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	without := New().Search(state, nil, Limits{Depth: 4})
+
+	engine := New()
+	engine.Table = transposition.New(4)
+	first := engine.Search(state, nil, Limits{Depth: 4})
+	if first.Nodes >= without.Nodes {
+		t.Errorf("search with a table visited %d nodes, %d without", first.Nodes, without.Nodes)
+	}
+	// the second search finds the first's results
+	second := engine.Search(state, nil, Limits{Depth: 4})
+	if second.Nodes >= first.Nodes {
+		t.Errorf("repeated search visited %d nodes, first %d", second.Nodes, first.Nodes)
+	}
+	if engine.Table.Stats().Hits == 0 {
+		t.Error("no table hits")
+	}
+}
+
+func TestSearchReturnsLegalMove(t *testing.T) {
+	game := models.NewChessGame()
+	result := New().Search(game.CurrentState, game.PositionHistory(), Limits{Depth: 2})
+	if !game.IsPossibleMove(result.BestMove) {
+		t.Errorf("best move %s is not legal", result.BestMove.UCI())
+	}
+}
+
+func TestSearchRespectsLimits(t *testing.T) {
+	state := models.NewChessState()
+
+	result := New().Search(state, nil, Limits{Nodes: 5000})
+	// the node limit is checked periodically and never interrupts the first iteration
+	if result.Depth < 1 || result.Nodes > 5000+2*checkInterval {
+		t.Errorf("node limited search reached depth %d with %d nodes", result.Depth, result.Nodes)
+	}
+
+	start := time.Now()
+	result = New().Search(state, nil, Limits{MoveTime: 100 * time.Millisecond})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("search with 100ms budget took %v", elapsed)
+	}
+	if result.Depth < 1 {
+		t.Errorf("timed search did not complete an iteration")
+	}
+}
+
+func TestSearchStop(t *testing.T) {
+	engine := New()
+	engine.OnIteration = func(result Result) {
+		if result.Depth == 2 {
+			engine.Stop()
+		}
+	}
+	result := engine.Search(models.NewChessState(), nil, Limits{})
+	if result.Depth != 2 {
+		t.Errorf("stopped search reported depth %d, want 2", result.Depth)
+	}
+}
+
+func TestSearchNoLegalMoves(t *testing.T) {
+	state := mustState(t, "R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1")
+	result := New().Search(state, nil, Limits{Depth: 3})
+	if result.Score != -MateScore || len(result.PV) != 0 {
+		t.Errorf("checkmated side got score %d and pv %v", result.Score, result.PV)
+	}
+}
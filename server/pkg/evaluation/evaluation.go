@@ -0,0 +1,365 @@
+package evaluation
+
+import (
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+// a pair of middlegame and endgame values, blended by the game phase
+type score struct {
+	mg, eg int
+}
+
+func (s score) add(other score) score {
+	return score{s.mg + other.mg, s.eg + other.eg}
+}
+
+func (s score) sub(other score) score {
+	return score{s.mg - other.mg, s.eg - other.eg}
+}
+
+func (s score) times(n int) score {
+	return score{s.mg * n, s.eg * n}
+}
+
+// blends the middlegame and endgame values, phase runs from maxPhase (all pieces on the board) down to 0
+func (s score) taper(phase int) int {
+	return (s.mg*phase + s.eg*(maxPhase-phase)) / maxPhase
+}
+
+// static evaluation of a position split into its terms
+// every value is in centipawns from white's point of view, positive when white is better
+type Evaluation struct {
+	Material      int `json:"material"`
+	PieceSquares  int `json:"pieceSquares"`
+	PawnStructure int `json:"pawnStructure"`
+	KingSafety    int `json:"kingSafety"`
+	Mobility      int `json:"mobility"`
+	// from 24 with every piece on the board down to 0 with only kings and pawns
+	Phase int `json:"phase"`
+	// sum of the terms, or 0 when neither side can mate
+	Total int `json:"total"`
+}
+
+// returns the static score of the position in centipawns from the point of view of the side to move
+// this is the score a search maximizes
+func Evaluate(state *models.ChessState) int {
+	total := Analyze(state).Total
+	if state.Turn == models.Black {
+		return -total
+	}
+	return total
+}
+
+// evaluates the position term by term from white's point of view
+func Analyze(state *models.ChessState) Evaluation {
+	e := newEvaluator(state.Board)
+
+	var material, pieceSquares, pawns, king, mobility score
+	for color := 0; color < 2; color++ {
+		sign := 1
+		if color == models.Black {
+			sign = -1
+		}
+		material = material.add(e.material(color).times(sign))
+		pieceSquares = pieceSquares.add(e.pieceSquares(color).times(sign))
+		pawns = pawns.add(e.pawnStructure(color).times(sign))
+		king = king.add(e.kingSafety(color).times(sign))
+		mobility = mobility.add(e.mobility[color].times(sign))
+	}
+
+	phase := e.phase()
+	evaluation := Evaluation{
+		Material:      material.taper(phase),
+		PieceSquares:  pieceSquares.taper(phase),
+		PawnStructure: pawns.taper(phase),
+		KingSafety:    king.taper(phase),
+		Mobility:      mobility.taper(phase),
+		Phase:         phase,
+	}
+	if !state.Board.IsInsufficientMaterial() {
+		evaluation.Total = material.add(pieceSquares).add(pawns).add(king).add(mobility).taper(phase)
+	}
+	return evaluation
+}
+
+// facts about the board shared by the evaluation terms, indexed by color
+type evaluator struct {
+	board *models.ChessBoard
+
+	pawnsOnFile [2][8]int
+	pawnAttacks [2][8][8]bool
+	kingSquare  [2]models.Location
+	pieceCounts [2][7]int
+	mobility    [2]score
+	kingAttacks [2]int // attack weight of the color's pieces on the squares around the enemy king
+	// row of each color's rearmost pawn on each file
+	rearmost [2][8]int
+}
+
+func newEvaluator(board *models.ChessBoard) *evaluator {
+	e := &evaluator{board: board}
+	for file := 0; file < 8; file++ {
+		// rows past the far end, so a file without pawns blocks nothing
+		e.rearmost[models.White][file] = 8
+		e.rearmost[models.Black][file] = -1
+	}
+
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			piece := board[i][j]
+			if piece == models.EmptySquare {
+				continue
+			}
+			color, kind := colorOf(piece), kindOf(piece)
+			e.pieceCounts[color][kind]++
+			switch kind {
+			case models.WhiteKing:
+				e.kingSquare[color] = models.Location{Row: i, Col: j}
+			case models.WhitePawn:
+				e.pawnsOnFile[color][j]++
+				forward := forwardOf(color)
+				if color == models.White && i < e.rearmost[color][j] || color == models.Black && i > e.rearmost[color][j] {
+					e.rearmost[color][j] = i
+				}
+				if onBoard(i+forward, j-1) {
+					e.pawnAttacks[color][i+forward][j-1] = true
+				}
+				if onBoard(i+forward, j+1) {
+					e.pawnAttacks[color][i+forward][j+1] = true
+				}
+			}
+		}
+	}
+
+	// mobility needs both kings and all pawn attacks, so it is counted once they are known
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			if piece := board[i][j]; piece != models.EmptySquare {
+				e.pieceMobility(piece, i, j)
+			}
+		}
+	}
+	return e
+}
+
+func (e *evaluator) phase() int {
+	phase := 0
+	for color := 0; color < 2; color++ {
+		for kind, weight := range phaseWeights {
+			phase += e.pieceCounts[color][kind] * weight
+		}
+	}
+	if phase > maxPhase {
+		// early promotions
+		phase = maxPhase
+	}
+	return phase
+}
+
+func (e *evaluator) material(color int) score {
+	var total score
+	for kind, value := range materialValues {
+		total = total.add(value.times(e.pieceCounts[color][kind]))
+	}
+	if e.pieceCounts[color][models.WhiteBishop] >= 2 {
+		total = total.add(bishopPair)
+	}
+	return total
+}
+
+func (e *evaluator) pieceSquares(color int) score {
+	var total score
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			piece := e.board[i][j]
+			if piece == models.EmptySquare || colorOf(piece) != color {
+				continue
+			}
+			// tables are drawn from white's side, black reads them upside down
+			row := 7 - i
+			if color == models.Black {
+				row = i
+			}
+			kind := kindOf(piece)
+			total = total.add(score{middlegameTables[kind][row][j], endgameTables[kind][row][j]})
+		}
+	}
+	return total
+}
+
+// penalizes doubled and isolated pawns and rewards passed pawns by how far they have advanced
+func (e *evaluator) pawnStructure(color int) score {
+	var total score
+	enemy := 1 - color
+	for file := 0; file < 8; file++ {
+		count := e.pawnsOnFile[color][file]
+		if count == 0 {
+			continue
+		}
+		if count > 1 {
+			total = total.add(doubledPawn.times(count - 1))
+		}
+		if (file == 0 || e.pawnsOnFile[color][file-1] == 0) && (file == 7 || e.pawnsOnFile[color][file+1] == 0) {
+			total = total.add(isolatedPawn.times(count))
+		}
+	}
+
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			if e.board[i][j] != pawnOf(color) {
+				continue
+			}
+			passed := true
+			for file := j - 1; file <= j+1 && passed; file++ {
+				if file < 0 || file > 7 {
+					continue
+				}
+				// an enemy pawn ahead on this or a neighbouring file can block or capture the pawn
+				enemyPawn := e.rearmost[enemy][file]
+				if color == models.White && enemyPawn > i || color == models.Black && enemyPawn < i {
+					passed = false
+				}
+			}
+			if passed {
+				rank := i
+				if color == models.Black {
+					rank = 7 - i
+				}
+				total = total.add(passedPawn[rank])
+			}
+		}
+	}
+	return total
+}
+
+// rewards a pawn shield in front of the king and penalizes open files next to it and enemy pieces attacking the squares around it
+// only counted in the middlegame, in the endgame the king is a fighting piece
+func (e *evaluator) kingSafety(color int) score {
+	king := e.kingSquare[color]
+	forward := forwardOf(color)
+	home := 0
+	if color == models.Black {
+		home = 7
+	}
+
+	safety := 0
+	if king.Row == home || king.Row == home+forward {
+		for file := king.Col - 1; file <= king.Col+1; file++ {
+			if file < 0 || file > 7 {
+				continue
+			}
+			if e.pawnsOnFile[color][file] == 0 {
+				safety += openFileNearKing
+				continue
+			}
+			if onBoard(king.Row+forward, file) && e.board[king.Row+forward][file] == pawnOf(color) {
+				safety += shieldPawnNear
+			} else if onBoard(king.Row+2*forward, file) && e.board[king.Row+2*forward][file] == pawnOf(color) {
+				safety += shieldPawnFar
+			}
+		}
+	}
+
+	// grows quickly so that several attackers count for more than the sum of each alone
+	attacks := e.kingAttacks[1-color]
+	safety -= attacks * attacks / 4
+	return score{safety, 0}
+}
+
+var (
+	knightJumps      = [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	bishopDirections = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	rookDirections   = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	allDirections    = [8][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}, {1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+)
+
+// counts the squares a knight, bishop, rook or queen can move to that are not covered by enemy pawns,
+// and the squares around the enemy king it attacks
+func (e *evaluator) pieceMobility(piece int8, i, j int) {
+	color, kind := colorOf(piece), kindOf(piece)
+	if kind == models.WhitePawn || kind == models.WhiteKing {
+		return
+	}
+	enemy := 1 - color
+	enemyKing := e.kingSquare[enemy]
+
+	squares, kingZone := 0, 0
+	visit := func(row, col int) {
+		target := e.board[row][col]
+		if (target == models.EmptySquare || colorOf(target) != color) && !e.pawnAttacks[enemy][row][col] {
+			squares++
+		}
+		if abs(row-enemyKing.Row) <= 1 && abs(col-enemyKing.Col) <= 1 {
+			kingZone++
+		}
+	}
+
+	var directions [][2]int
+	switch kind {
+	case models.WhiteKnight:
+		for _, jump := range knightJumps {
+			if onBoard(i+jump[0], j+jump[1]) {
+				visit(i+jump[0], j+jump[1])
+			}
+		}
+	case models.WhiteBishop:
+		directions = bishopDirections[:]
+	case models.WhiteRook:
+		directions = rookDirections[:]
+	case models.WhiteQueen:
+		directions = allDirections[:]
+	}
+	for _, direction := range directions {
+		row, col := i+direction[0], j+direction[1]
+		for onBoard(row, col) {
+			visit(row, col)
+			if e.board[row][col] != models.EmptySquare {
+				break
+			}
+			row, col = row+direction[0], col+direction[1]
+		}
+	}
+
+	e.mobility[color] = e.mobility[color].add(mobilityWeights[kind].times(squares - mobilityBaseline[kind]))
+	e.kingAttacks[color] += kingZone * kingAttackWeights[kind]
+}
+
+func colorOf(piece int8) int {
+	if piece < 0 {
+		return models.Black
+	}
+	return models.White
+}
+
+func kindOf(piece int8) int8 {
+	if piece < 0 {
+		return -piece
+	}
+	return piece
+}
+
+func pawnOf(color int) int8 {
+	if color == models.Black {
+		return models.BlackPawn
+	}
+	return models.WhitePawn
+}
+
+// returns the row direction a color's pawns move in
+func forwardOf(color int) int {
+	if color == models.Black {
+		return -1
+	}
+	return 1
+}
+
+func onBoard(row, col int) bool {
+	return row >= 0 && row < 8 && col >= 0 && col < 8
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,125 @@
+package evaluation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+func mustState(t *testing.T, fen string) *models.ChessState {
+	t.Helper()
+	state, err := models.NewChessStateFromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+// returns the FEN of the position with the colors swapped and the board flipped top to bottom
+func mirrorFEN(fen string) string {
+	fields := strings.Fields(fen)
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	swapCase := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' {
+				return r - 'a' + 'A'
+			}
+			if r >= 'A' && r <= 'Z' {
+				return r - 'A' + 'a'
+			}
+			return r
+		}, s)
+	}
+	fields[0] = swapCase(strings.Join(ranks, "/"))
+	if fields[1] == "w" {
+		fields[1] = "b"
+	} else {
+		fields[1] = "w"
+	}
+	if fields[2] != "-" {
+		fields[2] = swapCase(fields[2])
+	}
+	if fields[3] != "-" {
+		fields[3] = fields[3][:1] + string("87654321"[fields[3][1]-'1'])
+	}
+	return strings.Join(fields, " ")
+}
+
+var evaluationPositions = []string{
+	models.StartingFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1",
+	"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+}
+
+func TestEvaluationIsSymmetric(t *testing.T) {
+	for _, fen := range evaluationPositions {
+		state := mustState(t, fen)
+		mirrored := mustState(t, mirrorFEN(fen))
+		if Analyze(state) != negate(Analyze(mirrored)) {
+			t.Errorf("%s: %+v, mirrored %+v", fen, Analyze(state), Analyze(mirrored))
+		}
+		if Evaluate(state) != Evaluate(mirrored) {
+			t.Errorf("%s: side to move scores %d, mirrored %d", fen, Evaluate(state), Evaluate(mirrored))
+		}
+	}
+}
+
+func negate(e Evaluation) Evaluation {
+	return Evaluation{-e.Material, -e.PieceSquares, -e.PawnStructure, -e.KingSafety, -e.Mobility, e.Phase, -e.Total}
+}
+
+func TestStartingPositionIsEqual(t *testing.T) {
+	evaluation := Analyze(models.NewChessState())
+	if evaluation.Total != 0 || evaluation.Phase != maxPhase {
+		t.Errorf("starting position evaluates to %+v", evaluation)
+	}
+}
+
+func TestEvaluationTerms(t *testing.T) {
+	tests := []struct {
+		name   string
+		better string
+		worse  string
+		term   func(Evaluation) int
+	}{
+		{"extra knight", "4k3/8/8/8/8/8/8/1N2K3 w - - 0 1", "4k3/8/8/8/8/8/8/4K3 w - - 0 1", func(e Evaluation) int { return e.Material }},
+		{"centralized knight", "4k3/8/8/8/3N4/8/8/4K3 w - - 0 1", "4k3/8/8/8/8/8/8/N3K3 w - - 0 1", func(e Evaluation) int { return e.PieceSquares }},
+		{"passed pawn", "4k3/8/8/3P4/8/8/8/4K3 w - - 0 1", "4k3/2p5/8/3P4/8/8/8/4K3 w - - 0 1", func(e Evaluation) int { return e.PawnStructure }},
+		{"undoubled pawns", "4k3/8/8/8/8/8/2PP4/4K3 w - - 0 1", "4k3/8/8/8/8/3P4/3P4/4K3 w - - 0 1", func(e Evaluation) int { return e.PawnStructure }},
+		{"pawn shield", "6k1/pppq1ppp/8/8/8/8/5PPP/3Q2K1 w - - 0 1", "6k1/pppq1ppp/8/8/8/5P2/6PP/3Q2K1 w - - 0 1", func(e Evaluation) int { return e.KingSafety }},
+		{"open rook", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "4k3/8/8/8/8/8/P7/R3K3 w - - 0 1", func(e Evaluation) int { return e.Mobility }},
+	}
+	for _, test := range tests {
+		better, worse := Analyze(mustState(t, test.better)), Analyze(mustState(t, test.worse))
+		if test.term(better) <= test.term(worse) {
+			t.Errorf("%s: %+v should beat %+v", test.name, better, worse)
+		}
+	}
+}
+
+func TestTaperedKingPlacement(t *testing.T) {
+	// a centralized king is a liability with queens on the board and an asset without them
+	middlegame := "rnbqkbnr/pppppppp/8/8/3K4/8/PPPPPPPP/RNBQ1BNR w kq - 0 1"
+	middlegameHome := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	if Analyze(mustState(t, middlegame)).PieceSquares >= Analyze(mustState(t, middlegameHome)).PieceSquares {
+		t.Errorf("centralized king is not penalized in the middlegame")
+	}
+	endgame := "4k3/pppp4/8/8/3K4/8/PPPP4/8 w - - 0 1"
+	endgameHome := "4k3/pppp4/8/8/8/8/PPPP4/4K3 w - - 0 1"
+	if Analyze(mustState(t, endgame)).PieceSquares <= Analyze(mustState(t, endgameHome)).PieceSquares {
+		t.Errorf("centralized king is not rewarded in the endgame")
+	}
+}
+
+func TestInsufficientMaterialIsDrawn(t *testing.T) {
+	if total := Analyze(mustState(t, "4k3/8/8/8/8/8/8/2B1K3 w - - 0 1")).Total; total != 0 {
+		t.Errorf("king and bishop against king evaluates to %d", total)
+	}
+}
@@ -0,0 +1,182 @@
+package evaluation
+
+import (
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+// material values indexed by unsigned piece value
+var materialValues = [7]score{
+	models.WhitePawn:   {100, 120},
+	models.WhiteKnight: {320, 300},
+	models.WhiteBishop: {330, 310},
+	models.WhiteRook:   {500, 550},
+	models.WhiteQueen:  {950, 1000},
+}
+
+// game phase contributed by each piece, the starting position has maxPhase
+var phaseWeights = [7]int{
+	models.WhiteKnight: 1,
+	models.WhiteBishop: 1,
+	models.WhiteRook:   2,
+	models.WhiteQueen:  4,
+}
+
+const maxPhase = 24
+
+// piece-square tables from white's point of view, drawn with rank 8 at the top and the a-file on the left
+// indexed by unsigned piece value, then [7-Row][Col] for white and [Row][Col] for black
+var middlegameTables = [7][8][8]int{
+	models.WhitePawn: {
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{50, 50, 50, 50, 50, 50, 50, 50},
+		{10, 10, 20, 30, 30, 20, 10, 10},
+		{5, 5, 10, 25, 25, 10, 5, 5},
+		{0, 0, 0, 20, 20, 0, 0, 0},
+		{5, -5, -10, 0, 0, -10, -5, 5},
+		{5, 10, 10, -20, -20, 10, 10, 5},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+	},
+	models.WhiteKnight: {
+		{-50, -40, -30, -30, -30, -30, -40, -50},
+		{-40, -20, 0, 0, 0, 0, -20, -40},
+		{-30, 0, 10, 15, 15, 10, 0, -30},
+		{-30, 5, 15, 20, 20, 15, 5, -30},
+		{-30, 0, 15, 20, 20, 15, 0, -30},
+		{-30, 5, 10, 15, 15, 10, 5, -30},
+		{-40, -20, 0, 5, 5, 0, -20, -40},
+		{-50, -40, -30, -30, -30, -30, -40, -50},
+	},
+	models.WhiteBishop: {
+		{-20, -10, -10, -10, -10, -10, -10, -20},
+		{-10, 0, 0, 0, 0, 0, 0, -10},
+		{-10, 0, 5, 10, 10, 5, 0, -10},
+		{-10, 5, 5, 10, 10, 5, 5, -10},
+		{-10, 0, 10, 10, 10, 10, 0, -10},
+		{-10, 10, 10, 10, 10, 10, 10, -10},
+		{-10, 5, 0, 0, 0, 0, 5, -10},
+		{-20, -10, -10, -10, -10, -10, -10, -20},
+	},
+	models.WhiteRook: {
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{5, 10, 10, 10, 10, 10, 10, 5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{0, 0, 0, 5, 5, 0, 0, 0},
+	},
+	models.WhiteQueen: {
+		{-20, -10, -10, -5, -5, -10, -10, -20},
+		{-10, 0, 0, 0, 0, 0, 0, -10},
+		{-10, 0, 5, 5, 5, 5, 0, -10},
+		{-5, 0, 5, 5, 5, 5, 0, -5},
+		{0, 0, 5, 5, 5, 5, 0, -5},
+		{-10, 5, 5, 5, 5, 5, 0, -10},
+		{-10, 0, 5, 0, 0, 0, 0, -10},
+		{-20, -10, -10, -5, -5, -10, -10, -20},
+	},
+	models.WhiteKing: {
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-20, -30, -30, -40, -40, -30, -30, -20},
+		{-10, -20, -20, -20, -20, -20, -20, -10},
+		{20, 20, 0, 0, 0, 0, 20, 20},
+		{20, 30, 10, 0, 0, 10, 30, 20},
+	},
+}
+
+var endgameTables = [7][8][8]int{
+	models.WhitePawn: {
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{80, 80, 80, 80, 80, 80, 80, 80},
+		{50, 50, 50, 50, 50, 50, 50, 50},
+		{30, 30, 30, 30, 30, 30, 30, 30},
+		{20, 20, 20, 20, 20, 20, 20, 20},
+		{10, 10, 10, 10, 10, 10, 10, 10},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+	},
+	models.WhiteKnight: {
+		{-50, -40, -30, -30, -30, -30, -40, -50},
+		{-40, -20, 0, 0, 0, 0, -20, -40},
+		{-30, 0, 10, 15, 15, 10, 0, -30},
+		{-30, 5, 15, 20, 20, 15, 5, -30},
+		{-30, 0, 15, 20, 20, 15, 0, -30},
+		{-30, 5, 10, 15, 15, 10, 5, -30},
+		{-40, -20, 0, 5, 5, 0, -20, -40},
+		{-50, -40, -30, -30, -30, -30, -40, -50},
+	},
+	models.WhiteBishop: {
+		{-20, -10, -10, -10, -10, -10, -10, -20},
+		{-10, 0, 0, 0, 0, 0, 0, -10},
+		{-10, 0, 5, 10, 10, 5, 0, -10},
+		{-10, 5, 5, 10, 10, 5, 5, -10},
+		{-10, 0, 10, 10, 10, 10, 0, -10},
+		{-10, 10, 10, 10, 10, 10, 10, -10},
+		{-10, 5, 0, 0, 0, 0, 5, -10},
+		{-20, -10, -10, -10, -10, -10, -10, -20},
+	},
+	models.WhiteRook: {
+		{5, 5, 5, 5, 5, 5, 5, 5},
+		{10, 10, 10, 10, 10, 10, 10, 10},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+	},
+	models.WhiteQueen: {
+		{-20, -10, -10, -5, -5, -10, -10, -20},
+		{-10, 0, 0, 0, 0, 0, 0, -10},
+		{-10, 0, 5, 5, 5, 5, 0, -10},
+		{-5, 0, 5, 5, 5, 5, 0, -5},
+		{-5, 0, 5, 5, 5, 5, 0, -5},
+		{-10, 0, 5, 5, 5, 5, 0, -10},
+		{-10, 0, 0, 0, 0, 0, 0, -10},
+		{-20, -10, -10, -5, -5, -10, -10, -20},
+	},
+	models.WhiteKing: {
+		{-50, -40, -30, -20, -20, -30, -40, -50},
+		{-30, -20, -10, 0, 0, -10, -20, -30},
+		{-30, -10, 20, 30, 30, 20, -10, -30},
+		{-30, -10, 30, 40, 40, 30, -10, -30},
+		{-30, -10, 30, 40, 40, 30, -10, -30},
+		{-30, -10, 20, 30, 30, 20, -10, -30},
+		{-30, -30, 0, 0, 0, 0, -30, -30},
+		{-50, -30, -30, -30, -30, -30, -30, -50},
+	},
+}
+
+// pawn structure
+var (
+	doubledPawn  = score{-10, -25}
+	isolatedPawn = score{-12, -18}
+	// bonus for a passed pawn by its rank counted from its own side, rank 1 being where pawns start
+	passedPawn = [8]score{{0, 0}, {5, 10}, {10, 20}, {15, 35}, {25, 60}, {40, 100}, {60, 150}, {0, 0}}
+	bishopPair = score{30, 50}
+)
+
+// king safety, middlegame only as attacks on the king matter less once the queens and rooks are gone
+const (
+	shieldPawnNear   = 12
+	shieldPawnFar    = 6
+	openFileNearKing = -15
+)
+
+// weight of each piece kind attacking the squares around the enemy king
+var kingAttackWeights = [7]int{
+	models.WhiteKnight: 2,
+	models.WhiteBishop: 2,
+	models.WhiteRook:   3,
+	models.WhiteQueen:  5,
+}
+
+// mobility, per square beyond the typical number of squares a piece reaches
+var (
+	mobilityWeights  = [7]score{models.WhiteKnight: {4, 4}, models.WhiteBishop: {5, 5}, models.WhiteRook: {2, 4}, models.WhiteQueen: {1, 2}}
+	mobilityBaseline = [7]int{models.WhiteKnight: 4, models.WhiteBishop: 6, models.WhiteRook: 6, models.WhiteQueen: 12}
+)
@@ -0,0 +1,312 @@
+package lobby
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/matchmaking"
+)
+
+const (
+	// number of finished games kept for listing
+	RecentLimit = 50
+
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+
+	// events buffered for each subscriber, events for subscribers that fall further behind are dropped
+	subscriberBuffer = 32
+)
+
+type Status string
+
+const (
+	// players waiting in the matchmaking queue, who have no game until they are paired
+	Seeking Status = "seeking"
+	// created and waiting for its players to join
+	Open     Status = "open"
+	Playing  Status = "playing"
+	Finished Status = "finished"
+)
+
+// returns the status with the name, false if there is none
+func ParseStatus(name string) (Status, bool) {
+	switch status := Status(strings.ToLower(name)); status {
+	case Seeking, Open, Playing, Finished:
+		return status, true
+	}
+	return "", false
+}
+
+// a game as it is listed in the lobby
+type Game struct {
+	ID      string    `json:"id"`
+	Status  Status    `json:"status"`
+	Players [2]string `json:"players"`
+	Rated   bool      `json:"rated"`
+	// e.g. "5+3", "-" for untimed games
+	TimeControl string `json:"timeControl"`
+	Moves       int    `json:"moves"`
+	// side to move, "white" or "black", "" once the game has finished
+	Turn string `json:"turn"`
+	// description of the result once the game has finished
+	Result  string    `json:"result,omitempty"`
+	Created time.Time `json:"created"`
+	// nil until the game has finished
+	Ended *time.Time `json:"ended,omitempty"`
+}
+
+type EventType string
+
+const (
+	GameCreated EventType = "created"
+	GameStarted EventType = "started"
+	GameEnded   EventType = "ended"
+
+	SeekAdded     EventType = "seekAdded"
+	SeekMatched   EventType = "seekMatched"
+	SeekCancelled EventType = "seekCancelled"
+)
+
+// pushed to subscribers as games and seeks come and go, with the game or the seek the event is about
+type Event struct {
+	Type EventType         `json:"type"`
+	Game *Game             `json:"game,omitempty"`
+	Seek *matchmaking.Seek `json:"seek,omitempty"`
+}
+
+// limits the games listed, zero fields match every game
+type Filter struct {
+	Status Status
+	// matches games with a player of this name, ignoring case
+	Player string
+	// "true" or "false" to list only rated or unrated games
+	Rated       string
+	TimeControl string
+}
+
+func (filter Filter) matches(game Game) bool {
+	if filter.Status != "" && game.Status != filter.Status {
+		return false
+	}
+	if filter.Player != "" && !strings.EqualFold(game.Players[0], filter.Player) && !strings.EqualFold(game.Players[1], filter.Player) {
+		return false
+	}
+	if filter.Rated != "" && (filter.Rated == "true") != game.Rated {
+		return false
+	}
+	if filter.TimeControl != "" && game.TimeControl != filter.TimeControl {
+		return false
+	}
+	return true
+}
+
+// games found by the matchmaker are always rated
+func (filter Filter) matchesSeek(seek matchmaking.Seek) bool {
+	if filter.Status != "" && filter.Status != Seeking {
+		return false
+	}
+	if filter.Player != "" && !strings.EqualFold(seek.Player, filter.Player) {
+		return false
+	}
+	if filter.Rated == "false" {
+		return false
+	}
+	if filter.TimeControl != "" && seek.TimeControl != filter.TimeControl {
+		return false
+	}
+	return true
+}
+
+// a page of listed games, along with every matching seek as the queue only holds players who are still connected
+type Page struct {
+	// longest waiting first
+	Seeks []matchmaking.Seek `json:"seeks"`
+	Games []Game             `json:"games"`
+	// number of games matching the filter across every page
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// the seeks in the matchmaking queue and the games being played and recently finished, safe for concurrent use
+type Lobby struct {
+	// returns the players waiting in the matchmaking queue
+	queue func() []matchmaking.Seek
+
+	mu sync.Mutex
+	// open and playing games
+	live map[string]Game
+	// most recently finished first
+	finished    []Game
+	subscribers map[chan Event]bool
+}
+
+// queue is called while listing, so must not use the lobby
+func New(queue func() []matchmaking.Seek) *Lobby {
+	return &Lobby{
+		queue:       queue,
+		live:        make(map[string]Game),
+		subscribers: make(map[chan Event]bool),
+	}
+}
+
+// lists a new game as open
+func (lobby *Lobby) Created(game Game) {
+	lobby.mu.Lock()
+	defer lobby.mu.Unlock()
+	game.Status = Open
+	lobby.live[game.ID] = game
+	lobby.publish(Event{Type: GameCreated, Game: &game})
+}
+
+// marks a game as being played
+func (lobby *Lobby) Started(gameID string, moves int, turn string) {
+	lobby.mu.Lock()
+	defer lobby.mu.Unlock()
+	game, ok := lobby.live[gameID]
+	if !ok {
+		return
+	}
+	game.Status = Playing
+	game.Moves = moves
+	game.Turn = turn
+	lobby.live[gameID] = game
+	lobby.publish(Event{Type: GameStarted, Game: &game})
+}
+
+// updates the moves of a game being played, without an event so subscribers are not sent every move
+func (lobby *Lobby) Moved(gameID string, moves int, turn string) {
+	lobby.mu.Lock()
+	defer lobby.mu.Unlock()
+	game, ok := lobby.live[gameID]
+	if !ok {
+		return
+	}
+	game.Moves = moves
+	game.Turn = turn
+	lobby.live[gameID] = game
+}
+
+// moves a game to the recently finished games
+func (lobby *Lobby) Ended(gameID string, moves int, result string) {
+	lobby.mu.Lock()
+	defer lobby.mu.Unlock()
+	game, ok := lobby.live[gameID]
+	if !ok {
+		return
+	}
+	delete(lobby.live, gameID)
+	game.Status = Finished
+	game.Moves = moves
+	game.Turn = ""
+	game.Result = result
+	ended := time.Now()
+	game.Ended = &ended
+	lobby.finished = append([]Game{game}, lobby.finished...)
+	if len(lobby.finished) > RecentLimit {
+		lobby.finished = lobby.finished[:RecentLimit]
+	}
+	lobby.publish(Event{Type: GameEnded, Game: &game})
+}
+
+// tells subscribers a player has joined the matchmaking queue
+func (lobby *Lobby) SeekAdded(seek matchmaking.Seek) {
+	lobby.mu.Lock()
+	defer lobby.mu.Unlock()
+	lobby.publish(Event{Type: SeekAdded, Seek: &seek})
+}
+
+// tells subscribers a player in the matchmaking queue has been paired, their game is announced when it is created
+func (lobby *Lobby) SeekMatched(seek matchmaking.Seek) {
+	lobby.mu.Lock()
+	defer lobby.mu.Unlock()
+	lobby.publish(Event{Type: SeekMatched, Seek: &seek})
+}
+
+// tells subscribers a player has left the matchmaking queue without being paired
+func (lobby *Lobby) SeekCancelled(seek matchmaking.Seek) {
+	lobby.mu.Lock()
+	defer lobby.mu.Unlock()
+	lobby.publish(Event{Type: SeekCancelled, Seek: &seek})
+}
+
+// returns the seeks matching the filter and a page of the matching games: open games, then games being played, newest first,
+// then recently finished games, most recently finished first
+// limits outside 1 to MaxPageSize are replaced by DefaultPageSize
+func (lobby *Lobby) List(filter Filter, offset int, limit int) Page {
+	if limit < 1 || limit > MaxPageSize {
+		limit = DefaultPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	seeks := make([]matchmaking.Seek, 0)
+	for _, seek := range lobby.queue() {
+		if filter.matchesSeek(seek) {
+			seeks = append(seeks, seek)
+		}
+	}
+
+	lobby.mu.Lock()
+	live := make([]Game, 0, len(lobby.live))
+	for _, game := range lobby.live {
+		if filter.matches(game) {
+			live = append(live, game)
+		}
+	}
+	matching := make([]Game, 0, len(live)+len(lobby.finished))
+	for _, game := range lobby.finished {
+		if filter.matches(game) {
+			matching = append(matching, game)
+		}
+	}
+	lobby.mu.Unlock()
+
+	sort.Slice(live, func(i, j int) bool {
+		if live[i].Status != live[j].Status {
+			return live[i].Status == Open
+		}
+		if !live[i].Created.Equal(live[j].Created) {
+			return live[i].Created.After(live[j].Created)
+		}
+		return live[i].ID < live[j].ID
+	})
+	matching = append(live, matching...)
+
+	page := Page{Seeks: seeks, Games: make([]Game, 0), Total: len(matching), Offset: offset, Limit: limit}
+	if offset < len(matching) {
+		end := offset + limit
+		if end > len(matching) {
+			end = len(matching)
+		}
+		page.Games = append(page.Games, matching[offset:end]...)
+	}
+	return page
+}
+
+// returns a channel of lobby events and a function to stop receiving them
+func (lobby *Lobby) Subscribe() (<-chan Event, func()) {
+	events := make(chan Event, subscriberBuffer)
+	lobby.mu.Lock()
+	lobby.subscribers[events] = true
+	lobby.mu.Unlock()
+	return events, func() {
+		lobby.mu.Lock()
+		delete(lobby.subscribers, events)
+		lobby.mu.Unlock()
+	}
+}
+
+// must be called with the lock held
+func (lobby *Lobby) publish(event Event) {
+	for events := range lobby.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
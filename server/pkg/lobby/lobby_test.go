@@ -0,0 +1,142 @@
+package lobby
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/matchmaking"
+)
+
+func TestLobbyListsGames(t *testing.T) {
+	lobby := New(func() []matchmaking.Seek { return nil })
+	events, unsubscribe := lobby.Subscribe()
+	defer unsubscribe()
+
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	lobby.Created(Game{ID: "1", Players: [2]string{"alice", "bobby"}, Rated: true, TimeControl: "5+3", Turn: "white", Created: start})
+	lobby.Created(Game{ID: "2", Players: [2]string{"carol", "Computer"}, TimeControl: "-", Turn: "white", Created: start.Add(time.Minute)})
+	lobby.Created(Game{ID: "3", Players: [2]string{"Alice", "carol"}, Rated: true, TimeControl: "5+3", Turn: "white", Created: start.Add(2 * time.Minute)})
+	lobby.Started("1", 0, "white")
+	lobby.Moved("1", 3, "black")
+	lobby.Started("2", 0, "white")
+	lobby.Ended("2", 4, "Black wins!")
+	// events for games the lobby does not know about are ignored
+	lobby.Started("missing", 0, "white")
+
+	want := []EventType{GameCreated, GameCreated, GameCreated, GameStarted, GameStarted, GameEnded}
+	for _, eventType := range want {
+		event := <-events
+		if event.Type != eventType {
+			t.Fatalf("event = %v, want %v", event.Type, eventType)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %+v", event)
+	default:
+	}
+
+	ids := func(page Page) []string {
+		var ids []string
+		for _, game := range page.Games {
+			ids = append(ids, game.ID)
+		}
+		return ids
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		offset int
+		limit  int
+		want   []string
+		total  int
+	}{
+		{"everything", Filter{}, 0, 0, []string{"3", "1", "2"}, 3},
+		{"open", Filter{Status: Open}, 0, 0, []string{"3"}, 1},
+		{"player ignoring case", Filter{Player: "ALICE"}, 0, 0, []string{"3", "1"}, 2},
+		{"unrated", Filter{Rated: "false"}, 0, 0, []string{"2"}, 1},
+		{"time control", Filter{TimeControl: "5+3"}, 0, 0, []string{"3", "1"}, 2},
+		{"second page", Filter{}, 1, 1, []string{"1"}, 3},
+		{"past the end", Filter{}, 5, 1, nil, 3},
+	}
+	for _, test := range tests {
+		page := lobby.List(test.filter, test.offset, test.limit)
+		got := ids(page)
+		if page.Total != test.total || len(got) != len(test.want) {
+			t.Errorf("%v: got %v of %d, want %v of %d", test.name, got, page.Total, test.want, test.total)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+				break
+			}
+		}
+	}
+
+	playing := lobby.List(Filter{Status: Playing}, 0, 0).Games[0]
+	if playing.Moves != 3 || playing.Turn != "black" {
+		t.Errorf("playing game = %+v", playing)
+	}
+	finished := lobby.List(Filter{Status: Finished}, 0, 0).Games[0]
+	if finished.Result != "Black wins!" || finished.Turn != "" || finished.Ended == nil {
+		t.Errorf("finished game = %+v", finished)
+	}
+}
+
+func TestLobbyListsSeeks(t *testing.T) {
+	queued := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	seeks := []matchmaking.Seek{
+		{ID: 1, Player: "alice", Rating: 1500, TimeControl: "5+3", Queued: queued},
+		{ID: 2, Player: "bobby", Rating: 1620, TimeControl: "-", Queued: queued.Add(time.Second)},
+	}
+	lobby := New(func() []matchmaking.Seek { return seeks })
+	events, unsubscribe := lobby.Subscribe()
+	defer unsubscribe()
+	lobby.Created(Game{ID: "1", Players: [2]string{"carol", "Computer"}, TimeControl: "-", Turn: "white", Created: queued})
+
+	lobby.SeekAdded(seeks[0])
+	lobby.SeekMatched(seeks[0])
+	lobby.SeekCancelled(seeks[1])
+	want := []EventType{GameCreated, SeekAdded, SeekMatched, SeekCancelled}
+	for _, eventType := range want {
+		event := <-events
+		if event.Type != eventType {
+			t.Fatalf("event = %v, want %v", event.Type, eventType)
+		}
+		if (event.Seek != nil) == (event.Game != nil) {
+			t.Errorf("%v event = %+v, want either a game or a seek", event.Type, event)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int
+		games  int
+	}{
+		{"everything", Filter{}, []int{1, 2}, 1},
+		{"seeking", Filter{Status: Seeking}, []int{1, 2}, 0},
+		{"open", Filter{Status: Open}, nil, 1},
+		{"player ignoring case", Filter{Player: "BOBBY"}, []int{2}, 0},
+		{"unrated", Filter{Rated: "false"}, nil, 1},
+		{"time control", Filter{TimeControl: "5+3"}, []int{1}, 0},
+	}
+	for _, test := range tests {
+		page := lobby.List(test.filter, 0, 0)
+		var got []int
+		for _, seek := range page.Seeks {
+			got = append(got, seek.ID)
+		}
+		if len(got) != len(test.want) || len(page.Games) != test.games {
+			t.Errorf("%v: got seeks %v and %d games, want %v and %d", test.name, got, len(page.Games), test.want, test.games)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%v: got seeks %v, want %v", test.name, got, test.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package matchmaking
+
+import (
+	"math"
+)
+
+// Glicko-2 constants, see http://www.glicko.net/glicko/glicko2.pdf
+const (
+	DefaultRating     = 1500.0
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+
+	// constrains how much the volatility can change, smaller values change it less
+	tau = 0.5
+	// converts between the Glicko and Glicko-2 scales
+	glicko2Scale = 173.7178
+	// convergence tolerance for the volatility iteration
+	epsilon = 0.000001
+	// deviation is never allowed to grow past the starting deviation
+	maxDeviation = DefaultDeviation
+)
+
+// a player's Glicko-2 rating, in the Glicko scale
+type Rating struct {
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	Volatility float64 `json:"volatility"`
+	Games      int     `json:"games"`
+}
+
+// returns the rating of a player who has not played yet
+func NewRating() Rating {
+	return Rating{Rating: DefaultRating, Deviation: DefaultDeviation, Volatility: DefaultVolatility}
+}
+
+// the outcome of one game against an opponent, Score is 1 for a win, 0.5 for a draw and 0 for a loss
+type Outcome struct {
+	Opponent Rating
+	Score    float64
+}
+
+// returns the rating after a rating period with the given games
+// a period without games only increases the deviation
+func (rating Rating) Update(outcomes []Outcome) Rating {
+	mu := (rating.Rating - DefaultRating) / glicko2Scale
+	phi := rating.Deviation / glicko2Scale
+	sigma := rating.Volatility
+
+	if len(outcomes) == 0 {
+		rating.Deviation = math.Min(math.Sqrt(phi*phi+sigma*sigma)*glicko2Scale, maxDeviation)
+		return rating
+	}
+
+	// estimated variance and improvement from the game outcomes
+	var inverseVariance, improvement float64
+	for _, outcome := range outcomes {
+		muJ := (outcome.Opponent.Rating - DefaultRating) / glicko2Scale
+		phiJ := outcome.Opponent.Deviation / glicko2Scale
+		g := glickoG(phiJ)
+		e := expectedScore(mu, muJ, g)
+		inverseVariance += g * g * e * (1 - e)
+		improvement += g * (outcome.Score - e)
+	}
+	v := 1 / inverseVariance
+	delta := v * improvement
+
+	newSigma := newVolatility(phi, sigma, v, delta)
+	phiStar := math.Sqrt(phi*phi + newSigma*newSigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*improvement
+
+	return Rating{
+		Rating:     newMu*glicko2Scale + DefaultRating,
+		Deviation:  math.Min(newPhi*glicko2Scale, maxDeviation),
+		Volatility: newSigma,
+		Games:      rating.Games + len(outcomes),
+	}
+}
+
+// returns the expected score against an opponent, between 0 and 1
+func (rating Rating) ExpectedScore(opponent Rating) float64 {
+	mu := (rating.Rating - DefaultRating) / glicko2Scale
+	muJ := (opponent.Rating - DefaultRating) / glicko2Scale
+	return expectedScore(mu, muJ, glickoG(opponent.Deviation/glicko2Scale))
+}
+
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func expectedScore(mu, muJ, g float64) float64 {
+	return 1 / (1 + math.Exp(-g*(mu-muJ)))
+}
+
+// finds the new volatility with the Illinois algorithm from step 5 of the Glicko-2 paper
+func newVolatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
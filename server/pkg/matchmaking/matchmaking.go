@@ -0,0 +1,304 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/clock"
+)
+
+const (
+	// largest rating difference accepted by a player who has just joined the queue
+	InitialWindow = 100.0
+	// how fast the accepted rating difference grows while waiting, in rating points per second
+	WindowGrowth = 10.0
+	// the accepted rating difference stops growing here
+	MaxWindow = 700.0
+
+	// how often waiting players look for an opponent again as their windows widen
+	retryInterval = 500 * time.Millisecond
+)
+
+var ErrAlreadyQueued = errors.New("player is already waiting for a game")
+
+// two players paired by the matchmaker and the game created for them
+type Match struct {
+	GameID string `json:"gameID"`
+	White  string `json:"white"`
+	Black  string `json:"black"`
+}
+
+// creates the game for a match, returning its ID
+type CreateGameFunc func(white string, black string, timeControl clock.TimeControl) (string, error)
+
+// a player waiting for a game
+type ticket struct {
+	id          int
+	player      string
+	rating      float64
+	timeControl clock.TimeControl
+	joined      time.Time
+	// receives the match, or the error creating its game
+	matched chan matchResult
+}
+
+type matchResult struct {
+	match Match
+	err   error
+}
+
+// returns the ticket as it is listed
+func (t *ticket) seek() Seek {
+	return Seek{ID: t.id, Player: t.player, Rating: t.rating, TimeControl: t.timeControl.String(), Queued: t.joined}
+}
+
+// returns the largest rating difference the player accepts after waiting until now
+func (t *ticket) window(now time.Time) float64 {
+	return math.Min(InitialWindow+WindowGrowth*now.Sub(t.joined).Seconds(), MaxWindow)
+}
+
+// a player waiting in the queue for an opponent
+type Seek struct {
+	// tells seeks apart, as guests may queue more than once
+	ID     int     `json:"id"`
+	Player string  `json:"player"`
+	Rating float64 `json:"rating"`
+	// e.g. "5+3", "-" for untimed games
+	TimeControl string    `json:"timeControl"`
+	Queued      time.Time `json:"queued"`
+}
+
+// a change to the queue, reported to Matchmaker.OnSeek
+type SeekEvent int
+
+const (
+	SeekAdded SeekEvent = iota
+	// paired with an opponent, their game is created afterwards
+	SeekMatched
+	// the player stopped waiting before they were paired
+	SeekCancelled
+)
+
+// the number of players waiting for one time control
+type QueueStatus struct {
+	TimeControl string `json:"timeControl"`
+	Waiting     int    `json:"waiting"`
+}
+
+// the state of the matchmaking queue, times are in milliseconds
+type Status struct {
+	Waiting int `json:"waiting"`
+	// how long the players waiting now have been waiting on average
+	AverageWait int64 `json:"averageWait"`
+	// how long matched players waited for their game on average
+	AverageMatchWait int64         `json:"averageMatchWait"`
+	Matches          int           `json:"matches"`
+	Queues           []QueueStatus `json:"queues"`
+}
+
+// pairs players waiting for a game, preferring opponents with close ratings and the same time control
+// safe for concurrent use
+type Matchmaker struct {
+	ratings *Ratings
+	create  CreateGameFunc
+	// told as players join and leave the queue, nil if nothing is listening
+	// it is called with the queue locked so must not block or use the matchmaker, and must be set before the matchmaker is used
+	OnSeek func(event SeekEvent, seek Seek)
+
+	mu      sync.Mutex
+	waiting []*ticket
+	// the ID of the last seek
+	lastSeek int
+	rng      *rand.Rand
+	// for the average wait of matched players
+	matches   int
+	totalWait time.Duration
+
+	// returns the current time, replaced in tests
+	now func() time.Time
+}
+
+func New(ratings *Ratings, create CreateGameFunc) *Matchmaker {
+	return &Matchmaker{
+		ratings: ratings,
+		create:  create,
+		rng:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:     time.Now,
+	}
+}
+
+// waits in the queue until the player is paired with an opponent and their game is created, or ctx ends
+// an empty player is an anonymous guest with the default rating, who may queue any number of times
+func (matchmaker *Matchmaker) Find(ctx context.Context, player string, timeControl clock.TimeControl) (Match, error) {
+	matchmaker.mu.Lock()
+	if player != "" {
+		for _, waiting := range matchmaker.waiting {
+			if waiting.player == player {
+				matchmaker.mu.Unlock()
+				return Match{}, ErrAlreadyQueued
+			}
+		}
+	}
+	matchmaker.lastSeek++
+	t := &ticket{
+		id:          matchmaker.lastSeek,
+		player:      player,
+		rating:      matchmaker.ratings.Get(player).Rating,
+		timeControl: timeControl,
+		joined:      matchmaker.now(),
+		matched:     make(chan matchResult, 1),
+	}
+	matchmaker.waiting = append(matchmaker.waiting, t)
+	matchmaker.seekEvent(SeekAdded, t)
+	paired := matchmaker.pair(t)
+	matchmaker.mu.Unlock()
+	matchmaker.start(paired)
+
+	retry := time.NewTicker(retryInterval)
+	defer retry.Stop()
+	for {
+		select {
+		case result := <-t.matched:
+			return result.match, result.err
+		case <-retry.C:
+			var paired *pairing
+			matchmaker.mu.Lock()
+			if matchmaker.indexOf(t) >= 0 {
+				paired = matchmaker.pair(t)
+			}
+			matchmaker.mu.Unlock()
+			matchmaker.start(paired)
+		case <-ctx.Done():
+			matchmaker.mu.Lock()
+			i := matchmaker.indexOf(t)
+			if i >= 0 {
+				matchmaker.remove(i)
+				matchmaker.seekEvent(SeekCancelled, t)
+			}
+			matchmaker.mu.Unlock()
+			if i >= 0 {
+				return Match{}, ctx.Err()
+			}
+			// paired just before giving up
+			result := <-t.matched
+			return result.match, result.err
+		}
+	}
+}
+
+// returns the players waiting in the queue, longest waiting first
+func (matchmaker *Matchmaker) Seeks() []Seek {
+	matchmaker.mu.Lock()
+	defer matchmaker.mu.Unlock()
+	seeks := make([]Seek, 0, len(matchmaker.waiting))
+	for _, t := range matchmaker.waiting {
+		seeks = append(seeks, t.seek())
+	}
+	return seeks
+}
+
+// returns the state of the queue
+func (matchmaker *Matchmaker) Status() Status {
+	matchmaker.mu.Lock()
+	defer matchmaker.mu.Unlock()
+
+	now := matchmaker.now()
+	status := Status{Waiting: len(matchmaker.waiting), Matches: matchmaker.matches, Queues: make([]QueueStatus, 0)}
+	var totalWait time.Duration
+	queues := make(map[string]int)
+	for _, t := range matchmaker.waiting {
+		totalWait += now.Sub(t.joined)
+		queues[t.timeControl.String()]++
+	}
+	if len(matchmaker.waiting) > 0 {
+		status.AverageWait = (totalWait / time.Duration(len(matchmaker.waiting))).Milliseconds()
+	}
+	if matchmaker.matches > 0 {
+		status.AverageMatchWait = (matchmaker.totalWait / time.Duration(matchmaker.matches)).Milliseconds()
+	}
+	for timeControl, waiting := range queues {
+		status.Queues = append(status.Queues, QueueStatus{TimeControl: timeControl, Waiting: waiting})
+	}
+	sort.Slice(status.Queues, func(i, j int) bool {
+		return status.Queues[i].TimeControl < status.Queues[j].TimeControl
+	})
+	return status
+}
+
+// two tickets taken out of the queue to play each other
+type pairing struct {
+	white, black *ticket
+}
+
+// pairs the waiting ticket with the closest rated opponent both of them accept, if there is one,
+// and takes both out of the queue; their game is created by start once the lock is released
+// must be called with the lock held
+func (matchmaker *Matchmaker) pair(t *ticket) *pairing {
+	now := matchmaker.now()
+	best := -1
+	bestDifference := math.Inf(1)
+	for i, other := range matchmaker.waiting {
+		if other == t || other.timeControl != t.timeControl || (other.player != "" && other.player == t.player) {
+			continue
+		}
+		difference := math.Abs(other.rating - t.rating)
+		if difference <= math.Min(t.window(now), other.window(now)) && difference < bestDifference {
+			best, bestDifference = i, difference
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+
+	opponent := matchmaker.waiting[best]
+	matchmaker.remove(best)
+	matchmaker.remove(matchmaker.indexOf(t))
+	matchmaker.seekEvent(SeekMatched, t)
+	matchmaker.seekEvent(SeekMatched, opponent)
+	matchmaker.matches += 2
+	matchmaker.totalWait += now.Sub(t.joined) + now.Sub(opponent.joined)
+
+	white, black := t, opponent
+	if matchmaker.rng.Intn(2) == 0 {
+		white, black = black, white
+	}
+	return &pairing{white: white, black: black}
+}
+
+// creates the game for a pairing and tells both players about it, does nothing for a nil pairing
+// must be called without the lock held, creating the game may be slow
+func (matchmaker *Matchmaker) start(paired *pairing) {
+	if paired == nil {
+		return
+	}
+	match := Match{White: paired.white.player, Black: paired.black.player}
+	gameID, err := matchmaker.create(match.White, match.Black, paired.white.timeControl)
+	match.GameID = gameID
+	paired.white.matched <- matchResult{match, err}
+	paired.black.matched <- matchResult{match, err}
+}
+
+// must be called with the lock held
+func (matchmaker *Matchmaker) seekEvent(event SeekEvent, t *ticket) {
+	if matchmaker.OnSeek != nil {
+		matchmaker.OnSeek(event, t.seek())
+	}
+}
+
+func (matchmaker *Matchmaker) indexOf(t *ticket) int {
+	for i, waiting := range matchmaker.waiting {
+		if waiting == t {
+			return i
+		}
+	}
+	return -1
+}
+
+func (matchmaker *Matchmaker) remove(i int) {
+	matchmaker.waiting = append(matchmaker.waiting[:i], matchmaker.waiting[i+1:]...)
+}
@@ -0,0 +1,298 @@
+package matchmaking
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/clock"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// the worked example from the Glicko-2 paper
+func TestGlickoExample(t *testing.T) {
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	updated := player.Update([]Outcome{
+		{Opponent: Rating{Rating: 1400, Deviation: 30}, Score: 1},
+		{Opponent: Rating{Rating: 1550, Deviation: 100}, Score: 0},
+		{Opponent: Rating{Rating: 1700, Deviation: 300}, Score: 0},
+	})
+	if math.Abs(updated.Rating-1464.06) > 0.01 {
+		t.Errorf("rating = %.2f, want 1464.06", updated.Rating)
+	}
+	if math.Abs(updated.Deviation-151.52) > 0.01 {
+		t.Errorf("deviation = %.2f, want 151.52", updated.Deviation)
+	}
+	if math.Abs(updated.Volatility-0.05999) > 0.00001 {
+		t.Errorf("volatility = %.5f, want 0.05999", updated.Volatility)
+	}
+	if updated.Games != 3 {
+		t.Errorf("games = %d, want 3", updated.Games)
+	}
+
+	idle := player.Update(nil)
+	if idle.Rating != player.Rating || idle.Deviation <= player.Deviation {
+		t.Errorf("a period without games gave %+v, want the same rating with a larger deviation", idle)
+	}
+}
+
+func TestRecordGame(t *testing.T) {
+	ratings := NewRatings()
+	if recorded, err := ratings.RecordGame("alice", "bob", models.WhiteWinsOnTime); !recorded || err != nil {
+		t.Fatalf("RecordGame returned %v, %v for a decided game", recorded, err)
+	}
+	alice, bob := ratings.Get("alice"), ratings.Get("bob")
+	if alice.Rating <= DefaultRating || bob.Rating >= DefaultRating {
+		t.Errorf("after a white win alice = %.1f and bob = %.1f", alice.Rating, bob.Rating)
+	}
+	if math.Abs(alice.Rating-DefaultRating-(DefaultRating-bob.Rating)) > 0.001 {
+		t.Errorf("equal ratings should move by the same amount, alice = %.3f and bob = %.3f", alice.Rating, bob.Rating)
+	}
+
+	ratings.RecordGame("alice", "bob", models.Stalemate)
+	if ratings.Get("alice").Rating >= alice.Rating {
+		t.Error("a draw against a lower rated player should lower the rating")
+	}
+
+	for _, test := range []struct {
+		white, black string
+		result       models.Result
+	}{
+		{"alice", "bob", models.ContinueGame},
+		{"", "bob", models.BlackWins},
+		{"alice", "alice", models.WhiteWins},
+	} {
+		if recorded, _ := ratings.RecordGame(test.white, test.black, test.result); recorded {
+			t.Errorf("RecordGame(%q, %q, %q) = true, want false", test.white, test.black, test.result)
+		}
+	}
+}
+
+func TestRatingsAreSaved(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ratings, err := LoadRatings(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ratings.RecordGame("alice", "bob", models.BlackWins); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadRatings(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, player := range []string{"alice", "bob"} {
+		if loaded.Get(player) != ratings.Get(player) {
+			t.Errorf("%v loaded as %+v, saved as %+v", player, loaded.Get(player), ratings.Get(player))
+		}
+	}
+	if loaded.Get("alice").Games != 1 {
+		t.Errorf("alice loaded with %d games, want 1", loaded.Get("alice").Games)
+	}
+}
+
+// a matchmaker that creates numbered games and runs on a clock the test controls
+func newTestMatchmaker(ratings *Ratings) (*Matchmaker, func(time.Duration)) {
+	var mu sync.Mutex
+	now := time.Now()
+	games := 0
+	matchmaker := New(ratings, func(white, black string, timeControl clock.TimeControl) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		games++
+		return fmt.Sprint(games), nil
+	})
+	matchmaker.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	return matchmaker, func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+// queues a player in the background, returning the channel their match arrives on
+func find(matchmaker *Matchmaker, ctx context.Context, player string, timeControl clock.TimeControl) chan Match {
+	matches := make(chan Match, 1)
+	go func() {
+		match, err := matchmaker.Find(ctx, player, timeControl)
+		if err == nil {
+			matches <- match
+		}
+		close(matches)
+	}()
+	return matches
+}
+
+// waits until the given number of players are in the queue
+func waitForQueue(t *testing.T, matchmaker *Matchmaker, waiting int) {
+	t.Helper()
+	for start := time.Now(); matchmaker.Status().Waiting != waiting; {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("queue has %d players, want %d", matchmaker.Status().Waiting, waiting)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCreateRunsOutsideLock(t *testing.T) {
+	var matchmaker *Matchmaker
+	matchmaker = New(NewRatings(), func(white, black string, timeControl clock.TimeControl) (string, error) {
+		// creating a game may be slow, the queue must stay usable meanwhile
+		return fmt.Sprint(matchmaker.Status().Matches), nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	blitz := clock.TimeControl{Initial: 3 * time.Minute}
+	first := find(matchmaker, ctx, "alice", blitz)
+	second := find(matchmaker, ctx, "bob", blitz)
+	for _, matches := range []chan Match{first, second} {
+		if match, ok := <-matches; !ok || match.GameID != "2" {
+			t.Errorf("got match %+v, %v", match, ok)
+		}
+	}
+}
+
+func TestMatchesSameTimeControl(t *testing.T) {
+	matchmaker, _ := newTestMatchmaker(NewRatings())
+	ctx := context.Background()
+	blitz := clock.TimeControl{Initial: 5 * time.Minute, Mode: clock.SuddenDeath}
+
+	alice := find(matchmaker, ctx, "alice", blitz)
+	waitForQueue(t, matchmaker, 1)
+	bob := find(matchmaker, ctx, "bob", clock.TimeControl{})
+	waitForQueue(t, matchmaker, 2)
+	if _, err := matchmaker.Find(ctx, "alice", blitz); err != ErrAlreadyQueued {
+		t.Errorf("queueing twice returned %v, want ErrAlreadyQueued", err)
+	}
+
+	status := matchmaker.Status()
+	if len(status.Queues) != 2 {
+		t.Errorf("status has %d queues, want 2: %+v", len(status.Queues), status)
+	}
+
+	carol := find(matchmaker, ctx, "carol", blitz)
+	first, second := <-alice, <-carol
+	if first != second || first.GameID == "" {
+		t.Fatalf("alice got %+v and carol got %+v, want the same match", first, second)
+	}
+	if !(first.White == "alice" && first.Black == "carol") && !(first.White == "carol" && first.Black == "alice") {
+		t.Errorf("match %+v is not between alice and carol", first)
+	}
+	select {
+	case match := <-bob:
+		t.Errorf("bob was matched with %+v without an opponent", match)
+	default:
+	}
+	if status := matchmaker.Status(); status.Waiting != 1 || status.Matches != 2 {
+		t.Errorf("status after a match = %+v, want 1 waiting and 2 matched", status)
+	}
+}
+
+func TestWindowWidens(t *testing.T) {
+	ratings := NewRatings()
+	// push the ratings apart until they are outside the initial window
+	for ratings.Get("strong").Rating-ratings.Get("weak").Rating <= InitialWindow*2 {
+		ratings.RecordGame("strong", "weak", models.WhiteWins)
+	}
+	matchmaker, advance := newTestMatchmaker(ratings)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	strong := find(matchmaker, ctx, "strong", clock.TimeControl{})
+	weak := find(matchmaker, ctx, "weak", clock.TimeControl{})
+	waitForQueue(t, matchmaker, 2)
+	time.Sleep(2 * retryInterval)
+	if matchmaker.Status().Waiting != 2 {
+		t.Fatal("players outside each other's window were matched straight away")
+	}
+
+	advance(time.Duration((MaxWindow-InitialWindow)/WindowGrowth) * time.Second)
+	select {
+	case match := <-strong:
+		if match != <-weak {
+			t.Error("players got different matches")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("players were not matched after their windows widened")
+	}
+	if status := matchmaker.Status(); status.AverageMatchWait <= 0 {
+		t.Errorf("average match wait = %d, want > 0", status.AverageMatchWait)
+	}
+}
+
+func TestFindCancelled(t *testing.T) {
+	matchmaker, _ := newTestMatchmaker(NewRatings())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := matchmaker.Find(ctx, "alice", clock.TimeControl{}); err != context.DeadlineExceeded {
+		t.Errorf("Find returned %v, want context.DeadlineExceeded", err)
+	}
+	if matchmaker.Status().Waiting != 0 {
+		t.Error("a cancelled player is still waiting")
+	}
+}
+
+func TestSeeks(t *testing.T) {
+	matchmaker, _ := newTestMatchmaker(NewRatings())
+	var mu sync.Mutex
+	var events []string
+	matchmaker.OnSeek = func(event SeekEvent, seek Seek) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, fmt.Sprintf("%d %v", event, seek.Player))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blitz := clock.TimeControl{Initial: 5 * time.Minute, Mode: clock.SuddenDeath}
+
+	alice := find(matchmaker, ctx, "alice", blitz)
+	waitForQueue(t, matchmaker, 1)
+	bobCtx, bobCancel := context.WithCancel(ctx)
+	bob := find(matchmaker, bobCtx, "bob", clock.TimeControl{})
+	waitForQueue(t, matchmaker, 2)
+
+	seeks := matchmaker.Seeks()
+	if len(seeks) != 2 || seeks[0].Player != "alice" || seeks[1].Player != "bob" {
+		t.Fatalf("seeks = %+v, want alice then bob", seeks)
+	}
+	if seek := seeks[0]; seek.Rating != DefaultRating || seek.TimeControl != blitz.String() || seek.Queued.IsZero() || seek.ID == seeks[1].ID {
+		t.Errorf("alice's seek = %+v", seek)
+	}
+
+	find(matchmaker, ctx, "carol", blitz)
+	<-alice
+	bobCancel()
+	<-bob
+	if seeks := matchmaker.Seeks(); len(seeks) != 0 {
+		t.Errorf("seeks after everyone left the queue = %+v", seeks)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		fmt.Sprintf("%d alice", SeekAdded),
+		fmt.Sprintf("%d bob", SeekAdded),
+		fmt.Sprintf("%d carol", SeekAdded),
+		fmt.Sprintf("%d carol", SeekMatched),
+		fmt.Sprintf("%d alice", SeekMatched),
+		fmt.Sprintf("%d bob", SeekCancelled),
+	}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
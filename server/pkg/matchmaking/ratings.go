@@ -0,0 +1,122 @@
+package matchmaking
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// holds each player's rating as JSON, keyed by player name
+var ratingsBucket = []byte("ratings")
+
+// ratings of every player, safe for concurrent use
+type Ratings struct {
+	mu      sync.Mutex
+	ratings map[string]Rating
+	// where changed ratings are saved, nil to keep them in memory only
+	db *bolt.DB
+}
+
+// returns empty ratings kept in memory only
+func NewRatings() *Ratings {
+	return &Ratings{ratings: make(map[string]Rating)}
+}
+
+// returns the ratings stored in an existing database, creating their bucket if needed
+// ratings changed by later games are saved to the database
+func LoadRatings(db *bolt.DB) (*Ratings, error) {
+	ratings := &Ratings{ratings: make(map[string]Rating), db: db}
+	err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(ratingsBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(player, data []byte) error {
+			var rating Rating
+			if err := json.Unmarshal(data, &rating); err != nil {
+				return err
+			}
+			ratings.ratings[string(player)] = rating
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// returns the player's rating, the default rating if they have not played a rated game
+func (ratings *Ratings) Get(player string) Rating {
+	ratings.mu.Lock()
+	defer ratings.mu.Unlock()
+	return ratings.get(player)
+}
+
+func (ratings *Ratings) get(player string) Rating {
+	if rating, ok := ratings.ratings[player]; ok {
+		return rating
+	}
+	return NewRating()
+}
+
+// updates both players' ratings from the result of a game between them, treating the game as its own rating period
+// returns false without changing anything if the game has not ended or a player is anonymous,
+// or if the new ratings could not be saved
+func (ratings *Ratings) RecordGame(white string, black string, result models.Result) (bool, error) {
+	score, ok := whiteScore(result)
+	if !ok || white == "" || black == "" || white == black {
+		return false, nil
+	}
+
+	ratings.mu.Lock()
+	defer ratings.mu.Unlock()
+	whiteRating, blackRating := ratings.get(white), ratings.get(black)
+	updated := map[string]Rating{
+		white: whiteRating.Update([]Outcome{{Opponent: blackRating, Score: score}}),
+		black: blackRating.Update([]Outcome{{Opponent: whiteRating, Score: 1 - score}}),
+	}
+	if err := ratings.save(updated); err != nil {
+		return false, err
+	}
+	for player, rating := range updated {
+		ratings.ratings[player] = rating
+	}
+	return true, nil
+}
+
+// writes ratings to the database, if there is one
+func (ratings *Ratings) save(updated map[string]Rating) error {
+	if ratings.db == nil {
+		return nil
+	}
+	return ratings.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(ratingsBucket)
+		for player, rating := range updated {
+			data, err := json.Marshal(rating)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(player), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// returns white's score for a finished game
+func whiteScore(result models.Result) (float64, bool) {
+	switch {
+	case result == models.WhiteWins || result == models.WhiteWinsOnTime:
+		return 1, true
+	case result == models.BlackWins || result == models.BlackWinsOnTime:
+		return 0, true
+	case result.IsDraw():
+		return 0.5, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,351 @@
+package models
+
+import (
+	"math/bits"
+)
+
+// a set of squares, bit (Row*8 + Col) is set for each square in the set
+// so bit 0 is a1, bit 7 is h1 and bit 63 is h8
+type Bitboard uint64
+
+// selects the implementation behind EnumerateMoves
+// both produce the same set of moves (possibly in a different order), the perft tests check this
+type MoveGenerator int
+
+const (
+	// the original square by square generator that tries each move on a copied board
+	MailboxGenerator MoveGenerator = iota
+	// the bitboard generator with pin and check aware legal move generation
+	BitboardGenerator
+)
+
+// ray directions, the first four move towards higher square indexes and direction+4 is the opposite direction
+const (
+	north = iota
+	east
+	northEast
+	northWest
+	south
+	west
+	southWest
+	southEast
+)
+
+var rayDeltas = [8][2]int{
+	north:     {1, 0},
+	east:      {0, 1},
+	northEast: {1, 1},
+	northWest: {1, -1},
+	south:     {-1, 0},
+	west:      {0, -1},
+	southWest: {-1, -1},
+	southEast: {-1, 1},
+}
+
+// precomputed attack tables
+var (
+	knightAttacks [64]Bitboard
+	kingAttacks   [64]Bitboard
+	pawnAttacks   [2][64]Bitboard // indexed by the color of the attacking pawn
+	rays          [8][64]Bitboard
+	// squares strictly between two squares on a shared line, empty if they don't share one
+	betweenSquares [64][64]Bitboard
+	// the whole line through two squares, empty if they don't share one
+	lineThrough [64][64]Bitboard
+)
+
+func init() {
+	onBoard := func(row, col int) bool {
+		return row >= 0 && row <= 7 && col >= 0 && col <= 7
+	}
+
+	for square := 0; square < 64; square++ {
+		row, col := square/8, square%8
+
+		for _, delta := range [8][2]int{{1, 2}, {1, -2}, {2, 1}, {2, -1}, {-1, 2}, {-1, -2}, {-2, 1}, {-2, -1}} {
+			if onBoard(row+delta[0], col+delta[1]) {
+				knightAttacks[square] |= squareBit(row+delta[0], col+delta[1])
+			}
+		}
+		for _, delta := range rayDeltas {
+			if onBoard(row+delta[0], col+delta[1]) {
+				kingAttacks[square] |= squareBit(row+delta[0], col+delta[1])
+			}
+		}
+		for _, colDelta := range []int{-1, 1} {
+			if onBoard(row+1, col+colDelta) {
+				pawnAttacks[White][square] |= squareBit(row+1, col+colDelta)
+			}
+			if onBoard(row-1, col+colDelta) {
+				pawnAttacks[Black][square] |= squareBit(row-1, col+colDelta)
+			}
+		}
+
+		for direction, delta := range rayDeltas {
+			var between Bitboard
+			for r, c := row+delta[0], col+delta[1]; onBoard(r, c); r, c = r+delta[0], c+delta[1] {
+				rays[direction][square] |= squareBit(r, c)
+				betweenSquares[square][r*8+c] = between
+				between |= squareBit(r, c)
+			}
+		}
+	}
+
+	for square := 0; square < 64; square++ {
+		for direction := 0; direction < 4; direction++ {
+			line := rays[direction][square] | rays[direction+4][square] | Bitboard(1)<<square
+			for other := rays[direction][square] | rays[direction+4][square]; other != 0; other &= other - 1 {
+				lineThrough[square][bits.TrailingZeros64(uint64(other))] = line
+			}
+		}
+	}
+}
+
+func squareBit(row, col int) Bitboard {
+	return Bitboard(1) << (row*8 + col)
+}
+
+// returns the index of the lowest square in the set
+func (b Bitboard) first() int {
+	return bits.TrailingZeros64(uint64(b))
+}
+
+// returns the squares attacked along one ray, stopping at (and including) the first occupied square
+func rayAttacks(direction, square int, occupied Bitboard) Bitboard {
+	attacks := rays[direction][square]
+	blockers := attacks & occupied
+	if blockers == 0 {
+		return attacks
+	}
+	var blocker int
+	if direction < south {
+		blocker = bits.TrailingZeros64(uint64(blockers))
+	} else {
+		blocker = 63 - bits.LeadingZeros64(uint64(blockers))
+	}
+	return attacks ^ rays[direction][blocker]
+}
+
+func rookAttacks(square int, occupied Bitboard) Bitboard {
+	return rayAttacks(north, square, occupied) | rayAttacks(east, square, occupied) |
+		rayAttacks(south, square, occupied) | rayAttacks(west, square, occupied)
+}
+
+func bishopAttacks(square int, occupied Bitboard) Bitboard {
+	return rayAttacks(northEast, square, occupied) | rayAttacks(northWest, square, occupied) |
+		rayAttacks(southEast, square, occupied) | rayAttacks(southWest, square, occupied)
+}
+
+// piece placement of a ChessBoard split into one bitboard per piece
+type bitboardPosition struct {
+	// indexed by color, then by piece kind - 1 (pawn, knight, bishop, rook, queen, king)
+	pieces   [2][6]Bitboard
+	occupied [2]Bitboard
+	all      Bitboard
+}
+
+const (
+	pawnIndex = iota
+	knightIndex
+	bishopIndex
+	rookIndex
+	queenIndex
+	kingIndex
+)
+
+func newBitboardPosition(board *ChessBoard) bitboardPosition {
+	var position bitboardPosition
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			piece := board[i][j]
+			if piece == EmptySquare {
+				continue
+			}
+			color := White
+			if piece < 0 {
+				color = Black
+			}
+			position.pieces[color][pieceKind(piece)-1] |= squareBit(i, j)
+			position.occupied[color] |= squareBit(i, j)
+		}
+	}
+	position.all = position.occupied[White] | position.occupied[Black]
+	return position
+}
+
+// returns the pieces of the given color attacking a square, with sliders blocked by the given occupancy
+func (position *bitboardPosition) attackersTo(square, color int, occupied Bitboard) Bitboard {
+	pieces := &position.pieces[color]
+	return (pawnAttacks[1-color][square] & pieces[pawnIndex]) |
+		(knightAttacks[square] & pieces[knightIndex]) |
+		(kingAttacks[square] & pieces[kingIndex]) |
+		(rookAttacks(square, occupied) & (pieces[rookIndex] | pieces[queenIndex])) |
+		(bishopAttacks(square, occupied) & (pieces[bishopIndex] | pieces[queenIndex]))
+}
+
+// returns every square attacked by the given color, with sliders blocked by the given occupancy
+func (position *bitboardPosition) attacksBy(color int, occupied Bitboard) Bitboard {
+	pieces := &position.pieces[color]
+	var attacks Bitboard
+	for b := pieces[pawnIndex]; b != 0; b &= b - 1 {
+		attacks |= pawnAttacks[color][b.first()]
+	}
+	for b := pieces[knightIndex]; b != 0; b &= b - 1 {
+		attacks |= knightAttacks[b.first()]
+	}
+	for b := pieces[bishopIndex] | pieces[queenIndex]; b != 0; b &= b - 1 {
+		attacks |= bishopAttacks(b.first(), occupied)
+	}
+	for b := pieces[rookIndex] | pieces[queenIndex]; b != 0; b &= b - 1 {
+		attacks |= rookAttacks(b.first(), occupied)
+	}
+	for b := pieces[kingIndex]; b != 0; b &= b - 1 {
+		attacks |= kingAttacks[b.first()]
+	}
+	return attacks
+}
+
+// appends a move for every target square, from a single origin square
+func appendBitboardMoves(moves []Move, from int, targets Bitboard) []Move {
+	for ; targets != 0; targets &= targets - 1 {
+		to := targets.first()
+		moves = append(moves, NewMove(Normal, from/8, from%8, to/8, to%8))
+	}
+	return moves
+}
+
+// appends a pawn move, expanding it into the four promotions on the last rank
+func appendPawnMove(moves []Move, from, to int) []Move {
+	if to/8 == 0 || to/8 == 7 {
+		return append(moves,
+			NewMove(PromoteQueen, from/8, from%8, to/8, to%8),
+			NewMove(PromoteRook, from/8, from%8, to/8, to%8),
+			NewMove(PromoteBishop, from/8, from%8, to/8, to%8),
+			NewMove(PromoteKnight, from/8, from%8, to/8, to%8))
+	}
+	return append(moves, NewMove(Normal, from/8, from%8, to/8, to%8))
+}
+
+// returns a slice of all legal moves using bitboards
+// pins and checks are worked out up front, so moves never need to be tried on a copied board
+// (en passant, which can expose the king along a rank, is the one exception)
+func (state *ChessState) enumerateMovesBitboard() []Move {
+	moves := make([]Move, 0, 64)
+	us := int(state.Turn)
+	them := 1 - us
+	position := newBitboardPosition(state.Board)
+	ours := &position.pieces[us]
+
+	if ours[kingIndex] == 0 {
+		return moves
+	}
+	king := ours[kingIndex].first()
+
+	// king moves, with the king removed so it can't hide behind itself from a slider
+	danger := position.attacksBy(them, position.all&^ours[kingIndex])
+	moves = appendBitboardMoves(moves, king, kingAttacks[king]&^position.occupied[us]&^danger)
+
+	checkers := position.attackersTo(king, them, position.all)
+	if bits.OnesCount64(uint64(checkers)) > 1 {
+		// only the king can escape a double check
+		return moves
+	}
+
+	// squares that resolve a single check (capture the checker or block), everything if not in check
+	checkMask := ^Bitboard(0)
+	if checkers != 0 {
+		checker := checkers.first()
+		checkMask = checkers | betweenSquares[king][checker]
+	}
+
+	// pieces pinned against the king may only move along the line of the pin
+	var pinned Bitboard
+	theirs := &position.pieces[them]
+	snipers := (rookAttacks(king, 0) & (theirs[rookIndex] | theirs[queenIndex])) |
+		(bishopAttacks(king, 0) & (theirs[bishopIndex] | theirs[queenIndex]))
+	for ; snipers != 0; snipers &= snipers - 1 {
+		blockers := betweenSquares[king][snipers.first()] & position.all
+		if bits.OnesCount64(uint64(blockers)) == 1 && blockers&position.occupied[us] != 0 {
+			pinned |= blockers
+		}
+	}
+	allowed := func(from int) Bitboard {
+		if pinned&(Bitboard(1)<<from) != 0 {
+			return checkMask & lineThrough[king][from]
+		}
+		return checkMask
+	}
+
+	targets := ^position.occupied[us]
+	for b := ours[knightIndex] &^ pinned; b != 0; b &= b - 1 {
+		// a pinned knight can never move
+		from := b.first()
+		moves = appendBitboardMoves(moves, from, knightAttacks[from]&targets&checkMask)
+	}
+	for b := ours[bishopIndex] | ours[queenIndex]; b != 0; b &= b - 1 {
+		from := b.first()
+		moves = appendBitboardMoves(moves, from, bishopAttacks(from, position.all)&targets&allowed(from))
+	}
+	for b := ours[rookIndex] | ours[queenIndex]; b != 0; b &= b - 1 {
+		from := b.first()
+		moves = appendBitboardMoves(moves, from, rookAttacks(from, position.all)&targets&allowed(from))
+	}
+
+	// pawns
+	forward, startRow := 8, 1
+	if us == Black {
+		forward, startRow = -8, 6
+	}
+	for b := ours[pawnIndex]; b != 0; b &= b - 1 {
+		from := b.first()
+		mask := allowed(from)
+
+		single := from + forward
+		if position.all&(Bitboard(1)<<single) == 0 {
+			if mask&(Bitboard(1)<<single) != 0 {
+				moves = appendPawnMove(moves, from, single)
+			}
+			double := single + forward
+			if from/8 == startRow && position.all&(Bitboard(1)<<double) == 0 && mask&(Bitboard(1)<<double) != 0 {
+				moves = append(moves, NewMove(Normal, from/8, from%8, double/8, double%8))
+			}
+		}
+
+		for captures := pawnAttacks[us][from] & position.occupied[them] & mask; captures != 0; captures &= captures - 1 {
+			moves = appendPawnMove(moves, from, captures.first())
+		}
+	}
+
+	// en passant, checked by replaying the occupancy change since removing two pawns from a rank can expose the king
+	if target := state.enPassantSquare; target != NoSquare {
+		to := target.Row*8 + target.Col
+		captured := to - forward
+		for b := pawnAttacks[them][to] & ours[pawnIndex]; b != 0; b &= b - 1 {
+			from := b.first()
+			occupied := position.all ^ Bitboard(1)<<from ^ Bitboard(1)<<captured | Bitboard(1)<<to
+			attackers := position.attackersTo(king, them, occupied) &^ (Bitboard(1) << captured)
+			if attackers == 0 {
+				moves = append(moves, NewMove(EnPassant, from/8, from%8, to/8, to%8))
+			}
+		}
+	}
+
+	// castling, never out of, through or into check
+	if checkers == 0 {
+		row := king / 8
+		if (us == White && state.whiteCanCastleShort) || (us == Black && state.blackCanCastleShort) {
+			path := squareBit(row, 5) | squareBit(row, 6)
+			if position.all&path == 0 && danger&path == 0 {
+				moves = append(moves, NewMove(CastleShort, row, 4, row, 6))
+			}
+		}
+		if (us == White && state.whiteCanCastleLong) || (us == Black && state.blackCanCastleLong) {
+			path := squareBit(row, 2) | squareBit(row, 3)
+			if position.all&(path|squareBit(row, 1)) == 0 && danger&path == 0 {
+				moves = append(moves, NewMove(CastleLong, row, 4, row, 2))
+			}
+		}
+	}
+
+	return moves
+}
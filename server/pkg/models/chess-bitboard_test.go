@@ -0,0 +1,95 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// walks the move tree checking both generators produce the same set of moves at every node
+func checkGeneratorParity(t *testing.T, state *ChessState, depth int) {
+	mailbox := state.EnumerateMovesWith(MailboxGenerator)
+	bitboard := state.EnumerateMovesWith(BitboardGenerator)
+
+	found := make(map[Move]bool, len(mailbox))
+	for _, move := range mailbox {
+		found[move] = true
+	}
+	if len(bitboard) != len(mailbox) {
+		t.Fatalf("%q: bitboard generated %d moves, mailbox generated %d", state.FEN(), len(bitboard), len(mailbox))
+	}
+	for _, move := range bitboard {
+		if !found[move] {
+			t.Fatalf("%q: bitboard generated %s which mailbox did not", state.FEN(), move.UCI())
+		}
+	}
+
+	if depth <= 1 {
+		return
+	}
+	for _, move := range mailbox {
+		undo := state.MakeMove(move)
+		checkGeneratorParity(t, state, depth-1)
+		state.UnmakeMove(move, undo)
+	}
+}
+
+func TestBitboardGeneratorParity(t *testing.T) {
+	for _, position := range perftPositions {
+		state, err := NewChessStateFromFEN(position.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", position.name, err)
+		}
+		checkGeneratorParity(t, state, 3)
+	}
+	for _, position := range perftEdgeCases {
+		state, err := NewChessStateFromFEN(position.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", position.name, err)
+		}
+		checkGeneratorParity(t, state, 3)
+	}
+}
+
+// reports perft nodes per second for a generator on Kiwipete
+func benchmarkPerft(b *testing.B, generator MoveGenerator) {
+	state, err := NewChessStateFromFEN(perftPositions[1].fen)
+	if err != nil {
+		b.Fatal(err)
+	}
+	state.SetMoveGenerator(generator)
+	var nodes int64
+	start := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nodes += state.Perft(3)
+	}
+	b.ReportMetric(float64(nodes)/time.Since(start).Seconds(), "nodes/s")
+}
+
+func BenchmarkPerftMailbox(b *testing.B) {
+	benchmarkPerft(b, MailboxGenerator)
+}
+
+func BenchmarkPerftBitboard(b *testing.B) {
+	benchmarkPerft(b, BitboardGenerator)
+}
+
+// reports move generation calls per second for a generator on Kiwipete
+func benchmarkEnumerateMoves(b *testing.B, generator MoveGenerator) {
+	state, err := NewChessStateFromFEN(perftPositions[1].fen)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		state.EnumerateMovesWith(generator)
+	}
+}
+
+func BenchmarkEnumerateMovesMailbox(b *testing.B) {
+	benchmarkEnumerateMoves(b, MailboxGenerator)
+}
+
+func BenchmarkEnumerateMovesBitboard(b *testing.B) {
+	benchmarkEnumerateMoves(b, BitboardGenerator)
+}
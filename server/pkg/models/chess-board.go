@@ -76,6 +76,14 @@ func (board *ChessBoard) IsSquareAttackedByWhite(i, j int) bool {
 	if i-2 >= 0 && j-1 >= 0 && board[i-2][j-1] == WhiteKnight {
 		return true
 	}
+	// check for king attacks
+	for x := i - 1; x <= i+1; x++ {
+		for y := j - 1; y <= j+1; y++ {
+			if x >= 0 && x <= 7 && y >= 0 && y <= 7 && board[x][y] == WhiteKing {
+				return true
+			}
+		}
+	}
 	// check for bishop/queen attacks
 	for x, y := i+1, j+1; x <= 7 && y <= 7; x, y = x+1, y+1 {
 		if board[x][y] == EmptySquare {
@@ -187,6 +195,14 @@ func (board *ChessBoard) IsSquareAttackedByBlack(i, j int) bool {
 	if i-2 >= 0 && j-1 >= 0 && board[i-2][j-1] == BlackKnight {
 		return true
 	}
+	// check for king attacks
+	for x := i - 1; x <= i+1; x++ {
+		for y := j - 1; y <= j+1; y++ {
+			if x >= 0 && x <= 7 && y >= 0 && y <= 7 && board[x][y] == BlackKing {
+				return true
+			}
+		}
+	}
 	// check for bishop/queen attacks
 	for x, y := i+1, j+1; x <= 7 && y <= 7; x, y = x+1, y+1 {
 		if board[x][y] == EmptySquare {
@@ -292,6 +308,52 @@ func (board *ChessBoard) IsBlackInCheck() bool {
 	return false
 }
 
+// returns true if neither side has enough material left to deliver checkmate:
+// king against king, king and a single minor piece against king, or kings and bishops all on one square color
+func (board *ChessBoard) IsInsufficientMaterial() bool {
+	knights := 0
+	bishops := 0
+	lightBishops := 0
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			switch board[i][j] {
+			case EmptySquare, WhiteKing, BlackKing:
+			case WhiteKnight, BlackKnight:
+				knights++
+			case WhiteBishop, BlackBishop:
+				bishops++
+				if (i+j)%2 == 1 {
+					lightBishops++
+				}
+			default:
+				// pawns, rooks and queens can always mate
+				return false
+			}
+		}
+	}
+
+	if knights+bishops <= 1 {
+		return true
+	}
+	return knights == 0 && (lightBishops == 0 || lightBishops == bishops)
+}
+
+// returns true if the color has no pieces other than its king
+func (board *ChessBoard) HasOnlyKing(color int8) bool {
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			piece := board[i][j]
+			if piece == EmptySquare || piece == WhiteKing || piece == BlackKing {
+				continue
+			}
+			if (piece > 0) == (color == White) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func executeMoveOnBoard(move Move, board ChessBoard) (ChessBoard, bool, bool, bool, bool) {
 
 	whiteCanCastleShort := true
@@ -303,14 +365,14 @@ func executeMoveOnBoard(move Move, board ChessBoard) (ChessBoard, bool, bool, bo
 		row := move.OldSquare.Row
 
 		// move king
-		board[6][row] = board[4][row]
+		board[row][6] = board[row][4]
 
 		// move rook
-		board[5][row] = board[7][row]
+		board[row][5] = board[row][7]
 
 		// clear spaces
-		board[4][row] = EmptySquare
-		board[7][row] = EmptySquare
+		board[row][4] = EmptySquare
+		board[row][7] = EmptySquare
 
 		if row == 0 {
 			whiteCanCastleShort = false
@@ -328,15 +390,14 @@ func executeMoveOnBoard(move Move, board ChessBoard) (ChessBoard, bool, bool, bo
 		row := move.OldSquare.Row
 
 		// move king
-		board[2][row] = board[4][row]
+		board[row][2] = board[row][4]
 
 		// move rook
-		board[3][row] = board[0][row]
+		board[row][3] = board[row][0]
 
 		// clear spaces
-		board[0][row] = EmptySquare
-		board[1][row] = EmptySquare
-		board[4][row] = EmptySquare
+		board[row][0] = EmptySquare
+		board[row][4] = EmptySquare
 
 		if row == 0 {
 			whiteCanCastleShort = false
@@ -398,19 +459,24 @@ func executeMoveOnBoard(move Move, board ChessBoard) (ChessBoard, bool, bool, bo
 	}
 
 	// make checks to see if move effects castling rights
+	// a single move can touch two rook corners (e.g. a rook capturing a rook), so each is checked separately
 	if movingPieceType == WhiteKing {
 		whiteCanCastleShort = false
 		whiteCanCastleLong = false
 	} else if movingPieceType == BlackKing {
 		blackCanCastleShort = false
 		blackCanCastleLong = false
-	} else if (move.OldSquare.Row == 0 && move.OldSquare.Col == 0) || (move.NewSquare.Row == 0 && move.NewSquare.Col == 0) {
+	}
+	if (move.OldSquare.Row == 0 && move.OldSquare.Col == 0) || (move.NewSquare.Row == 0 && move.NewSquare.Col == 0) {
 		whiteCanCastleLong = false
-	} else if (move.OldSquare.Row == 0 && move.OldSquare.Col == 7) || (move.NewSquare.Row == 0 && move.NewSquare.Col == 7) {
+	}
+	if (move.OldSquare.Row == 0 && move.OldSquare.Col == 7) || (move.NewSquare.Row == 0 && move.NewSquare.Col == 7) {
 		whiteCanCastleShort = false
-	} else if (move.OldSquare.Row == 7 && move.OldSquare.Col == 0) || (move.NewSquare.Row == 7 && move.NewSquare.Col == 0) {
+	}
+	if (move.OldSquare.Row == 7 && move.OldSquare.Col == 0) || (move.NewSquare.Row == 7 && move.NewSquare.Col == 0) {
 		blackCanCastleLong = false
-	} else if (move.OldSquare.Row == 7 && move.OldSquare.Col == 7) || (move.NewSquare.Row == 7 && move.NewSquare.Col == 7) {
+	}
+	if (move.OldSquare.Row == 7 && move.OldSquare.Col == 7) || (move.NewSquare.Row == 7 && move.NewSquare.Col == 7) {
 		blackCanCastleShort = false
 	}
 	// make move
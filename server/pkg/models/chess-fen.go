@@ -0,0 +1,297 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FEN of the standard starting position
+const StartingFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// setup mappings between FEN piece letters and board values
+var fenPieces = map[byte]int8{
+	'K': WhiteKing,
+	'Q': WhiteQueen,
+	'R': WhiteRook,
+	'B': WhiteBishop,
+	'N': WhiteKnight,
+	'P': WhitePawn,
+	'k': BlackKing,
+	'q': BlackQueen,
+	'r': BlackRook,
+	'b': BlackBishop,
+	'n': BlackKnight,
+	'p': BlackPawn,
+}
+
+var fenLetters = map[int8]byte{
+	WhiteKing:   'K',
+	WhiteQueen:  'Q',
+	WhiteRook:   'R',
+	WhiteBishop: 'B',
+	WhiteKnight: 'N',
+	WhitePawn:   'P',
+	BlackKing:   'k',
+	BlackQueen:  'q',
+	BlackRook:   'r',
+	BlackBishop: 'b',
+	BlackKnight: 'n',
+	BlackPawn:   'p',
+}
+
+// creates a game state from a FEN string
+// the halfmove clock and fullmove number may be omitted, in which case they default to 0 and 1
+func NewChessStateFromFEN(fen string) (*ChessState, error) {
+	fields := strings.Fields(fen)
+	if len(fields) != 6 && len(fields) != 4 {
+		return nil, fmt.Errorf("invalid FEN %q: expected 6 fields (or 4 without move counters), found %d", fen, len(fields))
+	}
+
+	board, err := parseFENBoard(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid FEN %q: %w", fen, err)
+	}
+
+	state := &ChessState{
+		Board:           board,
+		enPassantSquare: NoSquare,
+		halfmoveClock:   0,
+		fullmoveNumber:  1,
+	}
+
+	// side to move
+	switch fields[1] {
+	case "w":
+		state.Turn = White
+	case "b":
+		state.Turn = Black
+	default:
+		return nil, fmt.Errorf("invalid FEN %q: side to move must be \"w\" or \"b\", found %q", fen, fields[1])
+	}
+
+	// castling rights
+	if fields[2] != "-" {
+		for i := 0; i < len(fields[2]); i++ {
+			var flag *bool
+			switch fields[2][i] {
+			case 'K':
+				flag = &state.whiteCanCastleShort
+			case 'Q':
+				flag = &state.whiteCanCastleLong
+			case 'k':
+				flag = &state.blackCanCastleShort
+			case 'q':
+				flag = &state.blackCanCastleLong
+			default:
+				return nil, fmt.Errorf("invalid FEN %q: unexpected castling character %q", fen, fields[2][i])
+			}
+			if *flag {
+				return nil, fmt.Errorf("invalid FEN %q: castling character %q repeated", fen, fields[2][i])
+			}
+			*flag = true
+		}
+	}
+	if err := state.validateCastlingRights(); err != nil {
+		return nil, fmt.Errorf("invalid FEN %q: %w", fen, err)
+	}
+
+	// en passant target
+	if fields[3] != "-" {
+		target, err := ParseSquare(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid FEN %q: en passant target: %w", fen, err)
+		}
+		if (state.Turn == White && target.Row != 5) || (state.Turn == Black && target.Row != 2) {
+			return nil, fmt.Errorf("invalid FEN %q: en passant target %s is not on the expected rank for the side to move", fen, fields[3])
+		}
+		if err := state.validateEnPassantTarget(target); err != nil {
+			return nil, fmt.Errorf("invalid FEN %q: %w", fen, err)
+		}
+		state.enPassantSquare = target
+	}
+
+	// move counters
+	if len(fields) == 6 {
+		halfmoveClock, err := strconv.Atoi(fields[4])
+		if err != nil || halfmoveClock < 0 {
+			return nil, fmt.Errorf("invalid FEN %q: halfmove clock must be a non-negative integer, found %q", fen, fields[4])
+		}
+		fullmoveNumber, err := strconv.Atoi(fields[5])
+		if err != nil || fullmoveNumber < 1 {
+			return nil, fmt.Errorf("invalid FEN %q: fullmove number must be a positive integer, found %q", fen, fields[5])
+		}
+		state.halfmoveClock = halfmoveClock
+		state.fullmoveNumber = fullmoveNumber
+	}
+
+	state.hash = state.ComputeHash()
+	return state, nil
+}
+
+// parses the piece placement field of a FEN string
+// ranks are listed from 8 down to 1, so the first rank in the string is row 7
+func parseFENBoard(placement string) (*ChessBoard, error) {
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("piece placement must have 8 ranks, found %d", len(ranks))
+	}
+
+	board := &ChessBoard{}
+	whiteKings, blackKings := 0, 0
+	for r, rank := range ranks {
+		i := 7 - r
+		if !fenRankFits(rank) {
+			return nil, fmt.Errorf("rank %d does not describe exactly 8 squares", i+1)
+		}
+		j := 0
+		for k := 0; k < len(rank); k++ {
+			c := rank[k]
+			if c >= '1' && c <= '8' {
+				for n := 0; n < int(c-'0'); n++ {
+					board[i][j] = EmptySquare
+					j++
+				}
+				continue
+			}
+			piece, ok := fenPieces[c]
+			if !ok {
+				return nil, fmt.Errorf("unexpected character %q on rank %d", c, i+1)
+			}
+			if (piece == WhitePawn || piece == BlackPawn) && (i == 0 || i == 7) {
+				return nil, fmt.Errorf("pawn on rank %d", i+1)
+			}
+			if piece == WhiteKing {
+				whiteKings++
+			} else if piece == BlackKing {
+				blackKings++
+			}
+			board[i][j] = piece
+			j++
+		}
+	}
+
+	if whiteKings != 1 || blackKings != 1 {
+		return nil, fmt.Errorf("expected one king per side, found %d white and %d black", whiteKings, blackKings)
+	}
+
+	return board, nil
+}
+
+// returns true if a FEN rank describes exactly 8 squares
+func fenRankFits(rank string) bool {
+	squares := 0
+	for k := 0; k < len(rank); k++ {
+		if rank[k] >= '1' && rank[k] <= '8' {
+			squares += int(rank[k] - '0')
+		} else {
+			squares++
+		}
+	}
+	return squares == 8
+}
+
+// returns an error if a castling flag is set without the king and rook on their starting squares
+func (state *ChessState) validateCastlingRights() error {
+	if (state.whiteCanCastleShort || state.whiteCanCastleLong) && state.Board[0][4] != WhiteKing {
+		return fmt.Errorf("white castling rights require the white king on e1")
+	}
+	if state.whiteCanCastleShort && state.Board[0][7] != WhiteRook {
+		return fmt.Errorf("white short castling requires a white rook on h1")
+	}
+	if state.whiteCanCastleLong && state.Board[0][0] != WhiteRook {
+		return fmt.Errorf("white long castling requires a white rook on a1")
+	}
+	if (state.blackCanCastleShort || state.blackCanCastleLong) && state.Board[7][4] != BlackKing {
+		return fmt.Errorf("black castling rights require the black king on e8")
+	}
+	if state.blackCanCastleShort && state.Board[7][7] != BlackRook {
+		return fmt.Errorf("black short castling requires a black rook on h8")
+	}
+	if state.blackCanCastleLong && state.Board[7][0] != BlackRook {
+		return fmt.Errorf("black long castling requires a black rook on a8")
+	}
+	return nil
+}
+
+// returns an error unless the target is the square a pawn of the side not to move just passed over with a double step:
+// the pawn is directly in front of the target, and the target and the square the pawn came from are empty
+func (state *ChessState) validateEnPassantTarget(target Location) error {
+	forward, pawn := -1, int8(BlackPawn)
+	if state.Turn == Black {
+		forward, pawn = 1, WhitePawn
+	}
+	if state.Board[target.Row+forward][target.Col] != pawn {
+		return fmt.Errorf("en passant target %s has no pawn in front of it that could have just moved two squares", target)
+	}
+	if state.Board[target.Row][target.Col] != EmptySquare || state.Board[target.Row-forward][target.Col] != EmptySquare {
+		return fmt.Errorf("en passant target %s must be empty, as must the square the pawn came from", target)
+	}
+	return nil
+}
+
+// returns the FEN string describing a ChessState
+func (state *ChessState) FEN() string {
+	var sb strings.Builder
+
+	// piece placement
+	for i := 7; i >= 0; i-- {
+		empty := 0
+		for j := 0; j < 8; j++ {
+			piece := state.Board[i][j]
+			if piece == EmptySquare {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteByte(fenLetters[piece])
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if i > 0 {
+			sb.WriteByte('/')
+		}
+	}
+
+	// side to move
+	if state.Turn == White {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+
+	// castling rights
+	castling := ""
+	if state.whiteCanCastleShort {
+		castling += "K"
+	}
+	if state.whiteCanCastleLong {
+		castling += "Q"
+	}
+	if state.blackCanCastleShort {
+		castling += "k"
+	}
+	if state.blackCanCastleLong {
+		castling += "q"
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	sb.WriteString(castling)
+
+	// en passant target
+	if state.enPassantSquare == NoSquare {
+		sb.WriteString(" -")
+	} else {
+		sb.WriteString(" " + state.enPassantSquare.String())
+	}
+
+	// move counters
+	fmt.Fprintf(&sb, " %d %d", state.halfmoveClock, state.fullmoveNumber)
+
+	return sb.String()
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFENRoundTrip(t *testing.T) {
+	fens := []string{
+		StartingFEN,
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/8/8/8/8/8/8/R3K2R b Kq - 12 40",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"4k3/8/8/8/8/8/8/4K3 b - - 99 150",
+	}
+	for _, fen := range fens {
+		state, err := NewChessStateFromFEN(fen)
+		if err != nil {
+			t.Errorf("NewChessStateFromFEN(%q): %v", fen, err)
+			continue
+		}
+		if got := state.FEN(); got != fen {
+			t.Errorf("FEN round trip of %q gave %q", fen, got)
+		}
+	}
+
+	// the move counters default when they are left out
+	state, err := NewChessStateFromFEN("4k3/8/8/8/8/8/8/4K3 w - -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := state.FEN(), "4k3/8/8/8/8/8/8/4K3 w - - 0 1"; got != want {
+		t.Errorf("FEN without move counters gave %q, want %q", got, want)
+	}
+}
+
+func TestFENAfterMoves(t *testing.T) {
+	game := NewChessGame()
+	playUCI(t, &game, "e2e4", "e7e6", "g1f3")
+	want := "rnbqkbnr/pppp1ppp/4p3/8/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"
+	if got := game.CurrentState.FEN(); got != want {
+		t.Errorf("FEN = %q, want %q", got, want)
+	}
+	state, err := NewChessStateFromFEN(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Hash() != game.CurrentState.Hash() {
+		t.Error("state parsed from the FEN hashes differently to the played position")
+	}
+}
+
+func TestFENErrors(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq", "expected 6 fields"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1", "8 ranks"},
+		{"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "exactly 8 squares"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNX w KQkq - 0 1", "unexpected character"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQQBNR w kq - 0 1", "one king per side"},
+		{"Pnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "pawn on rank 8"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1", "side to move"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkx - 0 1", "unexpected castling character"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KKQkq - 0 1", "repeated"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN1 w KQkq - 0 1", "rook on h1"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq z9 0 1", "en passant target"},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e6 0 1", "expected rank"},
+		// nothing could have just moved through d6
+		{"4k3/8/8/4P3/8/8/8/4K3 w - d6 0 1", "no pawn in front"},
+		{"4k3/3p4/8/3pP3/8/8/8/4K3 w - d6 0 1", "must be empty"},
+		{"4k3/8/3n4/3pP3/8/8/8/4K3 w - d6 0 1", "must be empty"},
+		{"4k3/8/8/8/3P4/8/8/4K3 b - e3 0 1", "no pawn in front"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - -1 1", "halfmove clock"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0", "fullmove number"},
+	}
+	for _, test := range tests {
+		_, err := NewChessStateFromFEN(test.fen)
+		if err == nil {
+			t.Errorf("NewChessStateFromFEN(%q) succeeded, want an error containing %q", test.fen, test.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("NewChessStateFromFEN(%q) = %q, want an error containing %q", test.fen, err, test.want)
+		}
+	}
+}
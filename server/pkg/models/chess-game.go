@@ -3,33 +3,142 @@ package models
 type Result string
 
 const (
-	ContinueGame Result = "C"
-	Stalemate    Result = "S"
-	WhiteWins    Result = "W"
-	BlackWins    Result = "B"
+	ContinueGame             Result = "C"
+	Stalemate                Result = "S"
+	DrawFiftyMoves           Result = "F"
+	DrawRepetition           Result = "R"
+	DrawInsufficientMaterial Result = "I"
+	WhiteWins                Result = "W"
+	BlackWins                Result = "B"
+	WhiteWinsOnTime          Result = "TW"
+	BlackWinsOnTime          Result = "TB"
+	// the side to move ran out of time but the opponent could not have checkmated
+	DrawTimeoutInsufficientMaterial Result = "TI"
+	// the game ended without a winner because it was never played or abandoned, e.g. a seat was never filled or neither player reconnected
+	Aborted Result = "A"
 )
 
+// returns true if the result is any kind of draw
+func (result Result) IsDraw() bool {
+	return result == Stalemate || result == DrawFiftyMoves || result == DrawRepetition || result == DrawInsufficientMaterial || result == DrawTimeoutInsufficientMaterial
+}
+
+// returns true if the game was ended by a player running out of time
+func (result Result) IsTimeout() bool {
+	return result == WhiteWinsOnTime || result == BlackWinsOnTime || result == DrawTimeoutInsufficientMaterial
+}
+
+// returns a human readable description of the result
+func (result Result) Description() string {
+	switch result {
+	case Stalemate:
+		return "Stalemate"
+	case DrawFiftyMoves:
+		return "Draw by the fifty-move rule"
+	case DrawRepetition:
+		return "Draw by threefold repetition"
+	case DrawInsufficientMaterial:
+		return "Draw by insufficient material"
+	case WhiteWins:
+		return "White wins!"
+	case BlackWins:
+		return "Black wins!"
+	case WhiteWinsOnTime:
+		return "White wins on time!"
+	case BlackWinsOnTime:
+		return "Black wins on time!"
+	case DrawTimeoutInsufficientMaterial:
+		return "Draw, time ran out but the opponent has insufficient material to win"
+	case Aborted:
+		return "Game aborted"
+	default:
+		return ""
+	}
+}
+
 type ChessGame struct {
+	StartingFEN   string
 	CurrentState  *ChessState
 	MoveHistory   []Move
 	PossibleMoves []Move
 	Winner        Result
+
+	// one entry per move in MoveHistory, used to take moves back
+	undoHistory []MoveUndo
+	// one entry per move in MoveHistory, the move in Standard Algebraic Notation
+	sanHistory []string
+	// hash of every position reached, including the starting one, used to detect repetitions
+	positionHistory []uint64
 }
 
 func NewChessGame() ChessGame {
 	game := ChessGame{
+		StartingFEN:  StartingFEN,
 		CurrentState: NewChessState(),
 		MoveHistory:  make([]Move, 0, 64),
 		Winner:       ContinueGame,
 	}
 	game.PossibleMoves = game.CurrentState.EnumerateMoves()
+	game.positionHistory = append(game.positionHistory, game.CurrentState.Hash())
 	return game
 }
 
+// creates a new game starting from the position described by a FEN string
+func NewChessGameFromFEN(fen string) (ChessGame, error) {
+	state, err := NewChessStateFromFEN(fen)
+	if err != nil {
+		return ChessGame{}, err
+	}
+	game := ChessGame{
+		StartingFEN:  state.FEN(),
+		CurrentState: state,
+		MoveHistory:  make([]Move, 0, 64),
+		Winner:       ContinueGame,
+	}
+	game.PossibleMoves = game.CurrentState.EnumerateMoves()
+	game.positionHistory = append(game.positionHistory, game.CurrentState.Hash())
+	game.updateWinner()
+	return game, nil
+}
+
 func (game *ChessGame) ExecuteMoveOnGame(move Move) {
+	game.sanHistory = append(game.sanHistory, game.CurrentState.MoveToSAN(move))
 	game.MoveHistory = append(game.MoveHistory, move)
-	game.CurrentState = game.CurrentState.ExecuteMoveOnState(move)
+	game.undoHistory = append(game.undoHistory, game.CurrentState.MakeMove(move))
 	game.PossibleMoves = game.CurrentState.EnumerateMoves()
+	game.positionHistory = append(game.positionHistory, game.CurrentState.Hash())
+	game.updateWinner()
+}
+
+// takes back the last move played, returning false if there is no move to take back
+func (game *ChessGame) UndoLastMove() bool {
+	last := len(game.MoveHistory) - 1
+	if last < 0 || last >= len(game.undoHistory) {
+		return false
+	}
+	game.CurrentState.UnmakeMove(game.MoveHistory[last], game.undoHistory[last])
+	game.MoveHistory = game.MoveHistory[:last]
+	game.undoHistory = game.undoHistory[:last]
+	game.sanHistory = game.sanHistory[:last]
+	game.positionHistory = game.positionHistory[:len(game.positionHistory)-1]
+	game.PossibleMoves = game.CurrentState.EnumerateMoves()
+	game.Winner = ContinueGame
+	game.updateWinner()
+	return true
+}
+
+// returns true if the move is one of the legal moves in the current position
+func (game *ChessGame) IsPossibleMove(move Move) bool {
+	for _, possibleMove := range game.PossibleMoves {
+		if move == possibleMove {
+			return true
+		}
+	}
+	return false
+}
+
+// sets the winner if the game has ended by checkmate or a draw rule
+func (game *ChessGame) updateWinner() {
 	if len(game.PossibleMoves) == 0 {
 		if game.CurrentState.Turn == White {
 			if game.CurrentState.Board.IsWhiteInCheck() {
@@ -44,5 +153,70 @@ func (game *ChessGame) ExecuteMoveOnGame(move Move) {
 				game.Winner = Stalemate
 			}
 		}
+		return
+	}
+
+	if game.CurrentState.Board.IsInsufficientMaterial() {
+		game.Winner = DrawInsufficientMaterial
+	} else if game.CurrentState.halfmoveClock >= 100 {
+		game.Winner = DrawFiftyMoves
+	} else if game.repetitions() >= 3 {
+		game.Winner = DrawRepetition
+	}
+}
+
+// ends the game because the side to move has run out of time
+// the opponent wins unless they have only their king left or neither side could ever checkmate
+func (game *ChessGame) Timeout() {
+	board := game.CurrentState.Board
+	if game.CurrentState.Turn == White {
+		game.Winner = BlackWinsOnTime
+		if board.IsInsufficientMaterial() || board.HasOnlyKing(Black) {
+			game.Winner = DrawTimeoutInsufficientMaterial
+		}
+	} else {
+		game.Winner = WhiteWinsOnTime
+		if board.IsInsufficientMaterial() || board.HasOnlyKing(White) {
+			game.Winner = DrawTimeoutInsufficientMaterial
+		}
 	}
 }
+
+// returns how many times the current position has occurred
+// only positions since the last capture or pawn move can repeat, so the search stops at the halfmove clock
+func (game *ChessGame) repetitions() int {
+	last := len(game.positionHistory) - 1
+	if last < 0 {
+		return 0
+	}
+	count := 0
+	for i := last; i >= 0 && last-i <= game.CurrentState.halfmoveClock; i -= 2 {
+		if game.positionHistory[i] == game.positionHistory[last] {
+			count++
+		}
+	}
+	return count
+}
+
+// returns the moves played so far in Standard Algebraic Notation
+// each move is written down as it is played, so this does not replay the game
+func (game *ChessGame) SANHistory() []string {
+	return append(make([]string, 0, len(game.sanHistory)), game.sanHistory...)
+}
+
+// returns the hashes of every position reached so far, oldest first and ending with the current position
+func (game *ChessGame) PositionHistory() []uint64 {
+	return append(make([]uint64, 0, len(game.positionHistory)), game.positionHistory...)
+}
+
+// returns a deep copy of the game that shares no state with the original
+func (game *ChessGame) Copy() ChessGame {
+	copied := *game
+	copied.CurrentState = game.CurrentState.Copy()
+	copied.MoveHistory = append(make([]Move, 0, len(game.MoveHistory)), game.MoveHistory...)
+	copied.PossibleMoves = append(make([]Move, 0, len(game.PossibleMoves)), game.PossibleMoves...)
+	copied.undoHistory = append(make([]MoveUndo, 0, len(game.undoHistory)), game.undoHistory...)
+	copied.sanHistory = append(make([]string, 0, len(game.sanHistory)), game.sanHistory...)
+	copied.positionHistory = append(make([]uint64, 0, len(game.positionHistory)), game.positionHistory...)
+	return copied
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestUndoLastMove(t *testing.T) {
+	game := NewChessGame()
+	playSAN(t, &game, "e4", "e5", "Qh5", "Nc6", "Bc4", "Nf6", "Qxf7#")
+	if game.Winner != WhiteWins {
+		t.Fatalf("winner after Qxf7# = %q, want %q", game.Winner, WhiteWins)
+	}
+
+	if !game.UndoLastMove() {
+		t.Fatal("UndoLastMove returned false with moves to undo")
+	}
+	if game.Winner != ContinueGame {
+		t.Errorf("winner after undo = %q, want %q", game.Winner, ContinueGame)
+	}
+	if len(game.MoveHistory) != 6 {
+		t.Errorf("move history has %d moves after undo, want 6", len(game.MoveHistory))
+	}
+	if want := "r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4"; game.CurrentState.FEN() != want {
+		t.Errorf("FEN after undo = %q, want %q", game.CurrentState.FEN(), want)
+	}
+	if len(game.PossibleMoves) == 0 {
+		t.Error("no possible moves after undo")
+	}
+
+	for game.UndoLastMove() {
+	}
+	if game.CurrentState.FEN() != StartingFEN {
+		t.Errorf("FEN after undoing every move = %q, want %q", game.CurrentState.FEN(), StartingFEN)
+	}
+}
+
+func TestInsufficientMaterial(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want bool
+	}{
+		{"8/8/4k3/8/8/3K4/8/8 w - - 0 1", true},
+		{"8/8/4k3/8/8/3K4/8/5N2 w - - 0 1", true},
+		{"8/8/4k3/8/8/3K4/8/5B2 w - - 0 1", true},
+		{"8/8/4k3/3b4/8/3K4/8/5B2 w - - 0 1", true},
+		{"8/8/4k3/4b3/8/3K4/8/5B2 w - - 0 1", false},
+		{"8/8/4k3/8/8/3K4/8/4NN2 w - - 0 1", false},
+		{"8/8/4k3/8/8/3K4/4P3/8 w - - 0 1", false},
+		{"8/8/4k3/8/8/3K4/8/4R3 w - - 0 1", false},
+	}
+	for _, test := range tests {
+		state, err := NewChessStateFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := state.Board.IsInsufficientMaterial(); got != test.want {
+			t.Errorf("IsInsufficientMaterial(%q) = %v, want %v", test.fen, got, test.want)
+		}
+	}
+}
+
+func TestDrawByInsufficientMaterial(t *testing.T) {
+	game, err := NewChessGameFromFEN("8/8/4k3/8/8/3K4/3n4/5N2 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	playSAN(t, &game, "Nxf1")
+	if game.Winner != DrawInsufficientMaterial {
+		t.Errorf("winner = %q, want %q", game.Winner, DrawInsufficientMaterial)
+	}
+}
+
+func TestDrawByFiftyMoves(t *testing.T) {
+	game, err := NewChessGameFromFEN("8/8/4k3/8/8/3K4/8/4R3 w - - 99 80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	playSAN(t, &game, "Ra1")
+	if game.Winner != DrawFiftyMoves {
+		t.Errorf("winner = %q, want %q", game.Winner, DrawFiftyMoves)
+	}
+}
+
+func TestDrawByRepetition(t *testing.T) {
+	game := NewChessGame()
+	playSAN(t, &game, "Nf3", "Nf6", "Ng1", "Ng8", "Nf3", "Nf6", "Ng1")
+	if game.Winner != ContinueGame {
+		t.Fatalf("winner after the position occurred twice = %q, want %q", game.Winner, ContinueGame)
+	}
+	playSAN(t, &game, "Ng8")
+	if game.Winner != DrawRepetition {
+		t.Errorf("winner = %q, want %q", game.Winner, DrawRepetition)
+	}
+	if game.Winner.Description() != "Draw by threefold repetition" {
+		t.Errorf("description = %q", game.Winner.Description())
+	}
+}
+
+func TestDrawByRepetitionWithPinnedEnPassantPawn(t *testing.T) {
+	game, err := NewChessGameFromFEN("4r2k/3p4/8/4P3/8/8/8/4K3 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// exd6 would expose the king to the rook, so the en passant target doesn't make the position after d5 different
+	playUCI(t, &game, "d7d5", "e1d1", "h8g8", "d1e1", "g8h8", "e1d1", "h8g8", "d1e1")
+	if game.Winner != ContinueGame {
+		t.Fatalf("winner after the position occurred twice = %q, want %q", game.Winner, ContinueGame)
+	}
+	playUCI(t, &game, "g8h8")
+	if game.Winner != DrawRepetition {
+		t.Errorf("winner = %q, want %q", game.Winner, DrawRepetition)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		fen  string
+		want Result
+	}{
+		{StartingFEN, BlackWinsOnTime},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", WhiteWinsOnTime},
+		// black has only a king and can never checkmate
+		{"8/8/4k3/8/8/3K4/4P3/8 w - - 0 1", DrawTimeoutInsufficientMaterial},
+		// a lone knight cannot force mate but could still mate with help from white's pawn
+		{"8/8/4k3/8/8/3K4/4P3/5n2 w - - 0 1", BlackWinsOnTime},
+		{"8/8/4k3/8/8/3K4/8/5N2 b - - 0 1", DrawTimeoutInsufficientMaterial},
+	}
+	for _, test := range tests {
+		game, err := NewChessGameFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		game.Timeout()
+		if game.Winner != test.want {
+			t.Errorf("%s: winner = %q, want %q", test.fen, game.Winner, test.want)
+		}
+		if !game.Winner.IsTimeout() {
+			t.Errorf("%s: IsTimeout() = false", test.fen)
+		}
+	}
+}
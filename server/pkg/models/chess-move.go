@@ -1,5 +1,10 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+)
+
 type MoveType int
 
 const (
@@ -18,6 +23,9 @@ type Location struct {
 	Col int
 }
 
+// sentinel for "no square", e.g. when there is no en passant target
+var NoSquare = Location{Row: -1, Col: -1}
+
 type Move struct {
 	Type      MoveType
 	OldSquare Location
@@ -37,3 +45,62 @@ func NewMove(moveType MoveType, oldI, oldJ, newI, newJ int) Move {
 		},
 	}
 }
+
+// returns the algebraic name of a square, e.g. "e4"
+func (location Location) String() string {
+	if location.Row < 0 || location.Row > 7 || location.Col < 0 || location.Col > 7 {
+		return "-"
+	}
+	return string([]byte{byte('a' + location.Col), byte('1' + location.Row)})
+}
+
+// parses an algebraic square name such as "e4"
+func ParseSquare(name string) (Location, error) {
+	if len(name) != 2 || name[0] < 'a' || name[0] > 'h' || name[1] < '1' || name[1] > '8' {
+		return NoSquare, fmt.Errorf("%q is not a valid square", name)
+	}
+	return Location{
+		Row: int(name[1] - '1'),
+		Col: int(name[0] - 'a'),
+	}, nil
+}
+
+// returns the move in UCI long algebraic notation, e.g. "e2e4" or "e7e8q"
+// castling is written as the king's move, e.g. "e1g1"
+func (move Move) UCI() string {
+	uci := move.OldSquare.String() + move.NewSquare.String()
+	switch move.Type {
+	case PromoteQueen:
+		uci += "q"
+	case PromoteRook:
+		uci += "r"
+	case PromoteBishop:
+		uci += "b"
+	case PromoteKnight:
+		uci += "n"
+	}
+	return uci
+}
+
+// resolves a move in UCI long algebraic notation to one of the legal moves in this state
+func (state *ChessState) ParseUCI(uci string) (Move, error) {
+	uci = strings.ToLower(uci)
+	if len(uci) != 4 && len(uci) != 5 {
+		return Move{}, fmt.Errorf("invalid UCI move %q", uci)
+	}
+	oldSquare, err := ParseSquare(uci[0:2])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid UCI move %q: %w", uci, err)
+	}
+	newSquare, err := ParseSquare(uci[2:4])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid UCI move %q: %w", uci, err)
+	}
+
+	for _, move := range state.EnumerateMoves() {
+		if move.OldSquare == oldSquare && move.NewSquare == newSquare && move.UCI() == uci {
+			return move, nil
+		}
+	}
+	return Move{}, fmt.Errorf("illegal move %q", uci)
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+// plays a sequence of UCI moves such as "e2e4", failing the test on the first illegal one
+func playUCI(t *testing.T, game *ChessGame, ucis ...string) {
+	t.Helper()
+	for _, uci := range ucis {
+		move, err := game.CurrentState.ParseUCI(uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		game.ExecuteMoveOnGame(move)
+	}
+}
+
+func TestSquareNames(t *testing.T) {
+	for row := 0; row < 8; row++ {
+		for col := 0; col < 8; col++ {
+			square := Location{Row: row, Col: col}
+			parsed, err := ParseSquare(square.String())
+			if err != nil || parsed != square {
+				t.Errorf("ParseSquare(%q) = %v, %v, want %v", square.String(), parsed, err, square)
+			}
+		}
+	}
+
+	tests := []struct {
+		square Location
+		name   string
+	}{
+		{Location{Row: 0, Col: 0}, "a1"},
+		{Location{Row: 3, Col: 4}, "e4"},
+		{Location{Row: 7, Col: 7}, "h8"},
+		{NoSquare, "-"},
+	}
+	for _, test := range tests {
+		if got := test.square.String(); got != test.name {
+			t.Errorf("%v.String() = %q, want %q", test.square, got, test.name)
+		}
+	}
+
+	for _, name := range []string{"", "e", "e9", "i1", "E4", "e44", "-"} {
+		if _, err := ParseSquare(name); err == nil {
+			t.Errorf("ParseSquare(%q) succeeded", name)
+		}
+	}
+}
+
+func TestUCI(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		uci  string
+		// the move as UCI() writes it if that differs
+		canonical string
+		moveType  MoveType
+	}{
+		{"pawn push", StartingFEN, "e2e4", "", Normal},
+		{"queen promotion", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8q", "", PromoteQueen},
+		{"knight promotion", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8n", "", PromoteKnight},
+		{"black promotion", "4k3/8/8/8/8/8/p7/4K3 b - - 0 1", "a2a1r", "", PromoteRook},
+		{"upper case", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "A7A8B", "a7a8b", PromoteBishop},
+		{"short castling as the king's move", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "", CastleShort},
+		{"long castling as the king's move", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "", CastleLong},
+		{"en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "", EnPassant},
+	}
+	for _, test := range tests {
+		state, err := NewChessStateFromFEN(test.fen)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		move, err := state.ParseUCI(test.uci)
+		if err != nil {
+			t.Errorf("%v: ParseUCI(%q): %v", test.name, test.uci, err)
+			continue
+		}
+		if move.Type != test.moveType {
+			t.Errorf("%v: ParseUCI(%q) has type %v, want %v", test.name, test.uci, move.Type, test.moveType)
+		}
+		canonical := test.canonical
+		if canonical == "" {
+			canonical = test.uci
+		}
+		if got := move.UCI(); got != canonical {
+			t.Errorf("%v: UCI() = %q, want %q", test.name, got, canonical)
+		}
+	}
+}
+
+func TestParseUCIErrors(t *testing.T) {
+	tests := []struct {
+		fen  string
+		uci  string
+		want string
+	}{
+		{StartingFEN, "e2", "invalid UCI move"},
+		{StartingFEN, "e2e4q5", "invalid UCI move"},
+		{StartingFEN, "z2e4", "not a valid square"},
+		{StartingFEN, "e2e5", "illegal move"},
+		{StartingFEN, "e2e4q", "illegal move"},
+		// promotions must say what they promote to
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8", "illegal move"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8k", "illegal move"},
+	}
+	for _, test := range tests {
+		state, err := NewChessStateFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = state.ParseUCI(test.uci)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("ParseUCI(%q) in %q = %v, want an error containing %q", test.uci, test.fen, err, test.want)
+		}
+	}
+}
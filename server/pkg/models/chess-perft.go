@@ -0,0 +1,37 @@
+package models
+
+// returns the number of leaf nodes of the legal move tree to the given depth
+// used to verify the move generator against known node counts
+// moves are made and unmade in place, so the state is unchanged once it returns
+func (state *ChessState) Perft(depth int) int64 {
+	if depth <= 0 {
+		return 1
+	}
+	moves := state.EnumerateMoves()
+	if depth == 1 {
+		return int64(len(moves))
+	}
+
+	var nodes int64
+	for _, move := range moves {
+		undo := state.MakeMove(move)
+		nodes += state.Perft(depth - 1)
+		state.UnmakeMove(move, undo)
+	}
+	return nodes
+}
+
+// returns the perft node count below each legal move, keyed by the move in UCI notation
+// comparing these against another engine narrows a perft mismatch down to a single move
+func (state *ChessState) Divide(depth int) map[string]int64 {
+	counts := make(map[string]int64)
+	if depth <= 0 {
+		return counts
+	}
+	for _, move := range state.EnumerateMoves() {
+		undo := state.MakeMove(move)
+		counts[move.UCI()] = state.Perft(depth - 1)
+		state.UnmakeMove(move, undo)
+	}
+	return counts
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"testing"
+)
+
+// known node counts from https://www.chessprogramming.org/Perft_Results
+var perftPositions = []struct {
+	name   string
+	fen    string
+	counts []int64 // counts[d-1] is the node count at depth d
+}{
+	{"start", StartingFEN, []int64{20, 400, 8902, 197281, 4865609}},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []int64{48, 2039, 97862, 4085603}},
+	{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []int64{14, 191, 2812, 43238, 674624}},
+	{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []int64{6, 264, 9467, 422333}},
+	{"position 4 mirrored", "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1", []int64{6, 264, 9467, 422333}},
+	{"position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []int64{44, 1486, 62379, 2103487}},
+	{"position 6", "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", []int64{46, 2079, 89890, 3894594}},
+}
+
+// positions targeting individual rules (castling, en passant, promotion, king adjacency)
+var perftEdgeCases = []struct {
+	name  string
+	fen   string
+	depth int
+	count int64
+}{
+	{"castling rights", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", 4, 314346},
+	{"en passant discovered check", "3k4/3p4/8/K1P4r/8/8/8/8 b - - 0 1", 6, 1134888},
+	{"en passant pinned", "8/8/1k6/2b5/2pP4/8/5K2/8 b - d3 0 1", 6, 1440467},
+	{"short castling gives check", "5k2/8/8/8/8/8/8/4K2R w K - 0 1", 6, 661072},
+	{"long castling gives check", "3k4/8/8/8/8/8/8/R3K3 w Q - 0 1", 6, 803711},
+	{"castling through attacked squares", "r3k2r/1b4bq/8/8/8/8/7B/R3K2R w KQkq - 0 1", 4, 1274206},
+	{"castling prevented", "r3k2r/8/3Q4/8/8/5q2/8/R3K2R b KQkq - 0 1", 4, 1720476},
+	{"promote out of check", "2K2r2/4P3/8/8/8/8/8/3k4 w - - 0 1", 6, 3821001},
+	{"underpromote to check", "8/P1k5/K7/8/8/8/8/8 w - - 0 1", 6, 92683},
+	{"self stalemate", "K1k5/8/P7/8/8/8/8/8 w - - 0 1", 6, 2217},
+	{"stalemate and checkmate", "8/k1P5/8/1K6/8/8/8/8 w - - 0 1", 7, 567584},
+}
+
+// node counts above this are skipped with -short
+const perftShortLimit = 500000
+
+var moveGenerators = []struct {
+	name      string
+	generator MoveGenerator
+}{
+	{"mailbox", MailboxGenerator},
+	{"bitboard", BitboardGenerator},
+}
+
+// runs a test once for each move generator
+func forEachGenerator(t *testing.T, test func(t *testing.T, generator MoveGenerator)) {
+	for _, generator := range moveGenerators {
+		generator := generator
+		t.Run(generator.name, func(t *testing.T) {
+			test(t, generator.generator)
+		})
+	}
+}
+
+func TestPerft(t *testing.T) {
+	forEachGenerator(t, func(t *testing.T, generator MoveGenerator) {
+		for _, position := range perftPositions {
+			state, err := NewChessStateFromFEN(position.fen)
+			if err != nil {
+				t.Fatalf("%s: %v", position.name, err)
+			}
+			state.SetMoveGenerator(generator)
+			for i, want := range position.counts {
+				if testing.Short() && want > perftShortLimit {
+					break
+				}
+				if got := state.Perft(i + 1); got != want {
+					t.Errorf("%s: perft(%d) = %d, want %d", position.name, i+1, got, want)
+				}
+			}
+		}
+	})
+}
+
+func TestPerftEdgeCases(t *testing.T) {
+	forEachGenerator(t, func(t *testing.T, generator MoveGenerator) {
+		for _, position := range perftEdgeCases {
+			if testing.Short() && position.count > perftShortLimit {
+				continue
+			}
+			state, err := NewChessStateFromFEN(position.fen)
+			if err != nil {
+				t.Fatalf("%s: %v", position.name, err)
+			}
+			state.SetMoveGenerator(generator)
+			if got := state.Perft(position.depth); got != position.count {
+				t.Errorf("%s: perft(%d) = %d, want %d", position.name, position.depth, got, position.count)
+			}
+		}
+	})
+}
+
+func TestDivideSumsToPerft(t *testing.T) {
+	state, err := NewChessStateFromFEN(perftPositions[1].fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := state.Divide(3)
+	if len(counts) != 48 {
+		t.Errorf("divide(3) has %d root moves, want 48", len(counts))
+	}
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	if total != 97862 {
+		t.Errorf("divide(3) sums to %d, want 97862", total)
+	}
+}
+
+func TestPerftDoesNotModifyState(t *testing.T) {
+	state, err := NewChessStateFromFEN(perftPositions[1].fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := state.FEN()
+	state.Perft(3)
+	if after := state.FEN(); after != before {
+		t.Errorf("perft changed the state from %q to %q", before, after)
+	}
+}
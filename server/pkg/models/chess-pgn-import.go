@@ -0,0 +1,227 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// a single game read from a PGN file
+// only the main line is kept, comments, NAGs and variations are discarded
+type PGNGame struct {
+	Index  int // zero based position of the game in the file
+	Tags   PGNTags
+	Moves  []string
+	Result string
+}
+
+// error raised while reading or replaying a PGN game
+// Ply is the one based half move the error occurred on, or 0 if it is not tied to a move
+type PGNError struct {
+	Game  int
+	Ply   int
+	Token string
+	Err   error
+}
+
+func (err *PGNError) Error() string {
+	if err.Ply == 0 {
+		if err.Token == "" {
+			return fmt.Sprintf("pgn game %d: %v", err.Game+1, err.Err)
+		}
+		return fmt.Sprintf("pgn game %d: token %q: %v", err.Game+1, err.Token, err.Err)
+	}
+	return fmt.Sprintf("pgn game %d, ply %d: move %q: %v", err.Game+1, err.Ply, err.Token, err.Err)
+}
+
+func (err *PGNError) Unwrap() error {
+	return err.Err
+}
+
+// parses every game in a PGN file
+func ParsePGN(text string) ([]PGNGame, error) {
+	games := make([]PGNGame, 0, 1)
+	current := PGNGame{Index: 0, Tags: PGNTags{}}
+	inMovetext := false
+	depth := 0
+
+	// finishes the current game and starts the next one
+	finish := func(result string) {
+		current.Result = result
+		games = append(games, current)
+		current = PGNGame{Index: len(games), Tags: PGNTags{}}
+		inMovetext = false
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+
+		case c == '%' && (i == 0 || text[i-1] == '\n'):
+			// escape mechanism, the rest of the line is ignored
+			i = skipLine(text, i)
+
+		case c == ';':
+			i = skipLine(text, i)
+
+		case c == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, &PGNError{Game: current.Index, Err: fmt.Errorf("unterminated comment")}
+			}
+			i += end + 1
+
+		case c == '(':
+			depth++
+			i++
+
+		case c == ')':
+			if depth == 0 {
+				return nil, &PGNError{Game: current.Index, Token: ")", Err: fmt.Errorf("unbalanced variation")}
+			}
+			depth--
+			i++
+
+		case c == '[' && depth == 0:
+			if inMovetext {
+				// a tag section without a termination marker for the previous game
+				finish("*")
+			}
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text) - i
+			}
+			name, value, err := parsePGNTag(text[i : i+end])
+			if err != nil {
+				return nil, &PGNError{Game: current.Index, Token: strings.TrimSpace(text[i : i+end]), Err: err}
+			}
+			current.Tags[name] = value
+			i += end
+
+		default:
+			// read a whole token
+			start := i
+			for i < len(text) && !strings.ContainsRune(" \t\r\n{}();[", rune(text[i])) {
+				i++
+			}
+			if i == start {
+				// stray delimiter such as a closing brace
+				return nil, &PGNError{Game: current.Index, Token: string(c), Err: fmt.Errorf("unexpected character")}
+			}
+			token := text[start:i]
+			if depth > 0 {
+				// moves inside variations are ignored
+				continue
+			}
+			inMovetext = true
+			switch {
+			case token == "1-0" || token == "0-1" || token == "1/2-1/2" || token == "*":
+				finish(token)
+			case token[0] == '$':
+				// numeric annotation glyph
+			default:
+				if san := stripMoveNumber(token); san != "" {
+					current.Moves = append(current.Moves, san)
+				}
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, &PGNError{Game: current.Index, Err: fmt.Errorf("unterminated variation")}
+	}
+	if inMovetext || len(current.Tags) > 0 {
+		finish("*")
+	}
+	return games, nil
+}
+
+// replays the game's moves on a new ChessGame, starting from the FEN tag if the game has one
+// moves that are not among the legal moves of the position are rejected
+func (pgnGame *PGNGame) Replay() (ChessGame, error) {
+	var game ChessGame
+	if fen, ok := pgnGame.Tags["FEN"]; ok {
+		var err error
+		game, err = NewChessGameFromFEN(fen)
+		if err != nil {
+			return ChessGame{}, &PGNError{Game: pgnGame.Index, Token: fen, Err: err}
+		}
+	} else {
+		game = NewChessGame()
+	}
+
+	for i, san := range pgnGame.Moves {
+		move, err := game.CurrentState.ParseSAN(san)
+		if err != nil {
+			return game, &PGNError{Game: pgnGame.Index, Ply: i + 1, Token: san, Err: err}
+		}
+		if !game.IsPossibleMove(move) {
+			return game, &PGNError{Game: pgnGame.Index, Ply: i + 1, Token: san, Err: fmt.Errorf("move is not among the possible moves")}
+		}
+		game.ExecuteMoveOnGame(move)
+	}
+	return game, nil
+}
+
+// parses and replays every game in a PGN file
+func ReplayPGN(text string) ([]ChessGame, error) {
+	pgnGames, err := ParsePGN(text)
+	if err != nil {
+		return nil, err
+	}
+	games := make([]ChessGame, 0, len(pgnGames))
+	for i := range pgnGames {
+		game, err := pgnGames[i].Replay()
+		if err != nil {
+			return games, err
+		}
+		games = append(games, game)
+	}
+	return games, nil
+}
+
+// parses a tag pair line such as [Event "Casual game"]
+func parsePGNTag(line string) (string, string, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasSuffix(line, "]") {
+		return "", "", fmt.Errorf("tag pair is not closed")
+	}
+	line = strings.TrimSpace(line[1 : len(line)-1])
+	space := strings.IndexAny(line, " \t")
+	if space <= 0 {
+		return "", "", fmt.Errorf("tag pair has no value")
+	}
+	name := line[:space]
+	value := strings.TrimSpace(line[space:])
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", "", fmt.Errorf("tag value must be a quoted string")
+	}
+	value = value[1 : len(value)-1]
+	value = strings.ReplaceAll(value, `\"`, `"`)
+	value = strings.ReplaceAll(value, `\\`, `\`)
+	return name, value, nil
+}
+
+// removes a leading move number indication ("12." or "12...") from a token
+func stripMoveNumber(token string) string {
+	i := 0
+	for i < len(token) && token[i] >= '0' && token[i] <= '9' {
+		i++
+	}
+	if i > 0 && i < len(token) && token[i] == '.' || i == len(token) {
+		for i < len(token) && token[i] == '.' {
+			i++
+		}
+		return token[i:]
+	}
+	return token
+}
+
+func skipLine(text string, i int) int {
+	end := strings.IndexByte(text[i:], '\n')
+	if end < 0 {
+		return len(text)
+	}
+	return i + end + 1
+}
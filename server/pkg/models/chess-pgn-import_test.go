@@ -0,0 +1,182 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const twoGamePGN = `% exported by hand
+[Event "First"]
+[White "alice"]
+[Black "bobby"]
+[Result "1-0"]
+
+1. e4 {King's pawn} e6 2. Nf3 $1 Nc6 (2... d6 3. d4 (3. Bc4) exd4) 3. Bb5 ; pinning the knight
+a6 4. Ba4!? Nf6 1-0
+
+[Event "Second"]
+[Black "Escaped \"quote\" and \\ backslash"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 30"]
+[SetUp "1"]
+
+30... Kd7 31. e4 Ke6 *
+`
+
+func TestParsePGN(t *testing.T) {
+	games, err := ParsePGN(twoGamePGN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("parsed %d games, want 2", len(games))
+	}
+
+	tests := []struct {
+		tags   PGNTags
+		moves  []string
+		result string
+	}{
+		{
+			PGNTags{"Event": "First", "White": "alice", "Black": "bobby", "Result": "1-0"},
+			[]string{"e4", "e6", "Nf3", "Nc6", "Bb5", "a6", "Ba4!?", "Nf6"},
+			"1-0",
+		},
+		{
+			PGNTags{"Event": "Second", "Black": `Escaped "quote" and \ backslash`, "FEN": "4k3/8/8/8/8/8/4P3/4K3 b - - 0 30", "SetUp": "1"},
+			[]string{"Kd7", "e4", "Ke6"},
+			"*",
+		},
+	}
+	for i, test := range tests {
+		game := games[i]
+		if game.Index != i {
+			t.Errorf("game %d has index %d", i, game.Index)
+		}
+		if len(game.Tags) != len(test.tags) {
+			t.Errorf("game %d tags = %v, want %v", i, game.Tags, test.tags)
+		}
+		for name, value := range test.tags {
+			if game.Tags[name] != value {
+				t.Errorf("game %d tag %v = %q, want %q", i, name, game.Tags[name], value)
+			}
+		}
+		if strings.Join(game.Moves, " ") != strings.Join(test.moves, " ") {
+			t.Errorf("game %d moves = %v, want %v", i, game.Moves, test.moves)
+		}
+		if game.Result != test.result {
+			t.Errorf("game %d result = %q, want %q", i, game.Result, test.result)
+		}
+	}
+}
+
+func TestReplayPGN(t *testing.T) {
+	games, err := ReplayPGN(twoGamePGN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"r1bqkb1r/1ppp1ppp/p1n1pn2/8/B3P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 2 5",
+		"8/8/4k3/8/4P3/8/8/4K3 w - - 1 32",
+	}
+	for i, game := range games {
+		if got := game.CurrentState.FEN(); got != want[i] {
+			t.Errorf("game %d ends at %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestPGNRoundTrip(t *testing.T) {
+	game := NewChessGame()
+	playUCI(t, &game, "e2e4", "d7d6", "d2d4", "g8f6", "b1c3", "g7g6", "g1f3", "f8g7", "f1e2", "e8g8", "e1g1", "b8c6", "c1g5", "c8g4", "g5f6", "g7f6", "d4d5", "g4f3", "e2f3", "c6e5")
+	pgn, err := game.PGN(PGNTags{"White": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	games, err := ReplayPGN(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 || games[0].CurrentState.FEN() != game.CurrentState.FEN() {
+		t.Errorf("replaying the exported PGN did not reach %q", game.CurrentState.FEN())
+	}
+}
+
+func TestPGNErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		pgn   string
+		want  string
+		game  int
+		ply   int
+		token string
+	}{
+		{
+			"illegal move in the second game",
+			"1. e4 e6 1-0\n\n[Event \"Second\"]\n\n1. d4 d6 2. Ke3 *\n",
+			`pgn game 2, ply 3: move "Ke3": illegal move "Ke3"`,
+			1, 3, "Ke3",
+		},
+		{
+			"ambiguous move",
+			"[FEN \"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1\"]\n\n1. Nd2 *\n",
+			`pgn game 1, ply 1: move "Nd2": ambiguous move "Nd2" matches 2 legal moves`,
+			0, 1, "Nd2",
+		},
+		{
+			"unparsable move",
+			"1. e4 e6 2. Zz9 *\n",
+			`pgn game 1, ply 3: move "Zz9": invalid SAN "Zz9"`,
+			0, 3, "Zz9",
+		},
+		{
+			"invalid FEN tag",
+			"[FEN \"8/8/8/8 w - - 0 1\"]\n\n*\n",
+			`pgn game 1: token "8/8/8/8 w - - 0 1": invalid FEN`,
+			0, 0, "8/8/8/8 w - - 0 1",
+		},
+		{
+			"unterminated comment",
+			"1. e4 *\n\n1. d4 {never closed\n",
+			"pgn game 2: unterminated comment",
+			1, 0, "",
+		},
+		{
+			"unbalanced variation",
+			"1. e4 e6) *\n",
+			`pgn game 1: token ")": unbalanced variation`,
+			0, 0, ")",
+		},
+		{
+			"unterminated variation",
+			"1. e4 (1. d4 *\n",
+			"pgn game 1: unterminated variation",
+			0, 0, "",
+		},
+		{
+			"unquoted tag value",
+			"[Event Casual]\n\n*\n",
+			`pgn game 1: token "[Event Casual]": tag value must be a quoted string`,
+			0, 0, "[Event Casual]",
+		},
+	}
+	for _, test := range tests {
+		_, err := ReplayPGN(test.pgn)
+		if err == nil {
+			t.Errorf("%v: no error, want %q", test.name, test.want)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("%v: error = %q, want it to start with %q", test.name, err, test.want)
+		}
+		var pgnErr *PGNError
+		if !errors.As(err, &pgnErr) {
+			t.Errorf("%v: error is a %T, not a *PGNError", test.name, err)
+			continue
+		}
+		if pgnErr.Game != test.game || pgnErr.Ply != test.ply || pgnErr.Token != test.token {
+			t.Errorf("%v: game %d, ply %d, token %q, want game %d, ply %d, token %q",
+				test.name, pgnErr.Game, pgnErr.Ply, pgnErr.Token, test.game, test.ply, test.token)
+		}
+	}
+}
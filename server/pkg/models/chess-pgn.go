@@ -0,0 +1,122 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// tag pairs written in the header of a PGN game, keyed by tag name
+type PGNTags map[string]string
+
+// the Seven Tag Roster, in the order the PGN standard requires them
+var sevenTagRoster = [7]string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// maximum line length for PGN export format
+const pgnLineLength = 80
+
+// returns the PGN game termination marker for a result
+func (result Result) PGNToken() string {
+	switch result {
+	case WhiteWins, WhiteWinsOnTime:
+		return "1-0"
+	case BlackWins, BlackWinsOnTime:
+		return "0-1"
+	case Stalemate, DrawFiftyMoves, DrawRepetition, DrawInsufficientMaterial, DrawTimeoutInsufficientMaterial:
+		return "1/2-1/2"
+	default:
+		return "*"
+	}
+}
+
+// returns the game in PGN export format
+// missing Seven Tag Roster entries are filled with "?" (or "????.??.??" for the date) and the Result tag is always taken from game.Winner
+func (game *ChessGame) PGN(tags PGNTags) (string, error) {
+	sans := game.SANHistory()
+	result := game.Winner.PGNToken()
+
+	var sb strings.Builder
+
+	// tag pair section
+	for _, name := range sevenTagRoster {
+		value, ok := tags[name]
+		if name == "Result" {
+			value = result
+		} else if (!ok || value == "") && name == "Date" {
+			value = "????.??.??"
+		} else if !ok || value == "" {
+			value = "?"
+		}
+		writePGNTag(&sb, name, value)
+	}
+	if game.StartingFEN != StartingFEN {
+		writePGNTag(&sb, "SetUp", "1")
+		writePGNTag(&sb, "FEN", game.StartingFEN)
+	}
+	extra := make([]string, 0, len(tags))
+	for name := range tags {
+		if !isSevenTagRoster(name) && name != "SetUp" && name != "FEN" {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		writePGNTag(&sb, name, tags[name])
+	}
+	sb.WriteByte('\n')
+
+	// movetext section
+	start, err := NewChessStateFromFEN(game.StartingFEN)
+	if err != nil {
+		return "", err
+	}
+	moveNumber := start.fullmoveNumber
+	turn := start.Turn
+	tokens := make([]string, 0, len(sans)*3/2+1)
+	for i, san := range sans {
+		if turn == White {
+			tokens = append(tokens, fmt.Sprintf("%d.", moveNumber))
+		} else if i == 0 {
+			tokens = append(tokens, fmt.Sprintf("%d...", moveNumber))
+		}
+		tokens = append(tokens, san)
+		if turn == Black {
+			moveNumber++
+			turn = White
+		} else {
+			turn = Black
+		}
+	}
+	tokens = append(tokens, result)
+
+	lineLength := 0
+	for _, token := range tokens {
+		if lineLength > 0 && lineLength+1+len(token) > pgnLineLength {
+			sb.WriteByte('\n')
+			lineLength = 0
+		} else if lineLength > 0 {
+			sb.WriteByte(' ')
+			lineLength++
+		}
+		sb.WriteString(token)
+		lineLength += len(token)
+	}
+	sb.WriteString("\n\n")
+
+	return sb.String(), nil
+}
+
+func writePGNTag(sb *strings.Builder, name, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	fmt.Fprintf(sb, "[%s \"%s\"]\n", name, value)
+}
+
+func isSevenTagRoster(name string) bool {
+	for _, tag := range sevenTagRoster {
+		if tag == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPGNExport(t *testing.T) {
+	game := NewChessGame()
+	playUCI(t, &game, "f2f3", "e7e6", "g2g4", "d8h4")
+
+	pgn, err := game.PGN(PGNTags{
+		"Event":       "Casual game",
+		"White":       "alice",
+		"Black":       `bobby "the fish"`,
+		"TimeControl": "300+3",
+		"Annotator":   "carol",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[Event "Casual game"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "alice"]
+[Black "bobby \"the fish\""]
+[Result "0-1"]
+[Annotator "carol"]
+[TimeControl "300+3"]
+
+1. f3 e6 2. g4 Qh4# 0-1
+
+`
+	if pgn != want {
+		t.Errorf("PGN =\n%s\nwant\n%s", pgn, want)
+	}
+}
+
+func TestPGNExportFromPosition(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/4P3/4K3 b - - 0 30"
+	game, err := NewChessGameFromFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	playUCI(t, &game, "e8d7", "e2e4")
+
+	pgn, err := game.PGN(PGNTags{"Result": "1-0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		// the Result tag always comes from the game
+		"[Result \"*\"]\n",
+		"[SetUp \"1\"]\n[FEN \"" + fen + "\"]\n",
+		// black moves first, so the first move number has an ellipsis
+		"\n30... Kd7 31. e4 *\n",
+	} {
+		if !strings.Contains(pgn, want) {
+			t.Errorf("PGN does not contain %q:\n%s", want, pgn)
+		}
+	}
+}
+
+func TestPGNExportWrapsLines(t *testing.T) {
+	game := NewChessGame()
+	for i := 0; i < 10; i++ {
+		playUCI(t, &game, "g1f3", "g8f6", "f3g1", "f6g8")
+		// avoid the repetition draw ending the game
+		game.Winner = ContinueGame
+	}
+	pgn, err := game.PGN(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	movetext := pgn[strings.Index(pgn, "\n\n")+2:]
+	lines := strings.Split(strings.TrimSpace(movetext), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("movetext was not wrapped:\n%s", movetext)
+	}
+	for _, line := range lines {
+		if len(line) > pgnLineLength {
+			t.Errorf("line is %d characters long: %q", len(line), line)
+		}
+	}
+}
+
+func TestResultPGNToken(t *testing.T) {
+	tests := []struct {
+		result Result
+		want   string
+	}{
+		{WhiteWins, "1-0"},
+		{BlackWins, "0-1"},
+		{BlackWinsOnTime, "0-1"},
+		{Stalemate, "1/2-1/2"},
+		{DrawRepetition, "1/2-1/2"},
+		{ContinueGame, "*"},
+		{Aborted, "*"},
+	}
+	for _, test := range tests {
+		if got := test.result.PGNToken(); got != test.want {
+			t.Errorf("%q.PGNToken() = %q, want %q", test.result, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// setup mappings between SAN piece letters and (unsigned) piece values
+var sanLetters = map[int8]string{
+	WhitePawn:   "",
+	WhiteKnight: "N",
+	WhiteBishop: "B",
+	WhiteRook:   "R",
+	WhiteQueen:  "Q",
+	WhiteKing:   "K",
+}
+
+var promotionLetters = map[MoveType]string{
+	PromoteQueen:  "Q",
+	PromoteRook:   "R",
+	PromoteBishop: "B",
+	PromoteKnight: "N",
+}
+
+var sanPieceKinds = map[byte]int8{
+	'N': WhiteKnight,
+	'B': WhiteBishop,
+	'R': WhiteRook,
+	'Q': WhiteQueen,
+	'K': WhiteKing,
+}
+
+var sanPromotions = map[byte]MoveType{
+	'Q': PromoteQueen,
+	'R': PromoteRook,
+	'B': PromoteBishop,
+	'N': PromoteKnight,
+}
+
+// piece, origin file, origin rank, capture, destination, promotion
+var sanPattern = regexp.MustCompile(`^([NBRQK])?([a-h])?([1-8])?(x)?([a-h][1-8])(?:=?([QRBN]))?$`)
+
+// returns the piece value with the color removed (always the white equivalent)
+func pieceKind(piece int8) int8 {
+	if piece < 0 {
+		return -piece
+	}
+	return piece
+}
+
+// returns true if the move captures a piece
+func (state *ChessState) IsCapture(move Move) bool {
+	if move.Type == EnPassant {
+		return true
+	}
+	if move.Type == CastleShort || move.Type == CastleLong {
+		return false
+	}
+	return state.Board[move.NewSquare.Row][move.NewSquare.Col] != EmptySquare
+}
+
+// returns the Standard Algebraic Notation for a legal move in this state, including check and mate suffixes
+func (state *ChessState) MoveToSAN(move Move) string {
+	var sb strings.Builder
+
+	if move.Type == CastleShort {
+		sb.WriteString("O-O")
+	} else if move.Type == CastleLong {
+		sb.WriteString("O-O-O")
+	} else {
+		kind := pieceKind(state.Board[move.OldSquare.Row][move.OldSquare.Col])
+		capture := state.IsCapture(move)
+
+		if kind == WhitePawn {
+			if capture {
+				sb.WriteByte(byte('a' + move.OldSquare.Col))
+			}
+		} else {
+			sb.WriteString(sanLetters[kind])
+			sb.WriteString(state.sanDisambiguation(move, kind))
+		}
+		if capture {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(move.NewSquare.String())
+		if letter, ok := promotionLetters[move.Type]; ok {
+			sb.WriteString("=" + letter)
+		}
+	}
+
+	sb.WriteString(state.checkSuffix(move))
+	return sb.String()
+}
+
+// returns the file, rank or square needed to tell a piece move apart from other legal moves of the same piece type
+func (state *ChessState) sanDisambiguation(move Move, kind int8) string {
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range state.EnumerateMoves() {
+		if other.NewSquare != move.NewSquare || other.OldSquare == move.OldSquare {
+			continue
+		}
+		if pieceKind(state.Board[other.OldSquare.Row][other.OldSquare.Col]) != kind {
+			continue
+		}
+		ambiguous = true
+		if other.OldSquare.Col == move.OldSquare.Col {
+			sameFile = true
+		}
+		if other.OldSquare.Row == move.OldSquare.Row {
+			sameRank = true
+		}
+	}
+
+	if !ambiguous {
+		return ""
+	}
+	if !sameFile {
+		return string(rune('a' + move.OldSquare.Col))
+	}
+	if !sameRank {
+		return string(rune('1' + move.OldSquare.Row))
+	}
+	return move.OldSquare.String()
+}
+
+// returns "+" if the move gives check, "#" if it gives checkmate and "" otherwise
+func (state *ChessState) checkSuffix(move Move) string {
+	next := *state
+	next.ExecuteMoveOnState(move)
+
+	var inCheck bool
+	if next.Turn == White {
+		inCheck = next.Board.IsWhiteInCheck()
+	} else {
+		inCheck = next.Board.IsBlackInCheck()
+	}
+	if !inCheck {
+		return ""
+	}
+	if len(next.EnumerateMoves()) == 0 {
+		return "#"
+	}
+	return "+"
+}
+
+// resolves a move in Standard Algebraic Notation to one of the legal moves in this state
+// check, mate and annotation suffixes are accepted and ignored
+func (state *ChessState) ParseSAN(san string) (Move, error) {
+	token := strings.TrimRight(san, "+#!?")
+	legalMoves := state.EnumerateMoves()
+
+	// castling, also accepting the zero variant used by some software
+	if token == "O-O" || token == "0-0" || token == "O-O-O" || token == "0-0-0" {
+		moveType := CastleShort
+		if len(token) == 5 {
+			moveType = CastleLong
+		}
+		for _, move := range legalMoves {
+			if move.Type == moveType {
+				return move, nil
+			}
+		}
+		return Move{}, fmt.Errorf("illegal move %q: castling is not possible", san)
+	}
+
+	parts := sanPattern.FindStringSubmatch(token)
+	if parts == nil {
+		return Move{}, fmt.Errorf("invalid SAN %q", san)
+	}
+
+	kind := int8(WhitePawn)
+	if parts[1] != "" {
+		kind = sanPieceKinds[parts[1][0]]
+	}
+	newSquare, _ := ParseSquare(parts[5])
+	promotion := Normal
+	if parts[6] != "" {
+		if kind != WhitePawn {
+			return Move{}, fmt.Errorf("invalid SAN %q: only pawns can promote", san)
+		}
+		promotion = sanPromotions[parts[6][0]]
+	}
+
+	var found []Move
+	for _, move := range legalMoves {
+		if move.NewSquare != newSquare || move.Type == CastleShort || move.Type == CastleLong {
+			continue
+		}
+		if pieceKind(state.Board[move.OldSquare.Row][move.OldSquare.Col]) != kind {
+			continue
+		}
+		if parts[2] != "" && move.OldSquare.Col != int(parts[2][0]-'a') {
+			continue
+		}
+		if parts[3] != "" && move.OldSquare.Row != int(parts[3][0]-'1') {
+			continue
+		}
+		if _, isPromotion := promotionLetters[move.Type]; isPromotion || promotion != Normal {
+			if move.Type != promotion {
+				continue
+			}
+		}
+		found = append(found, move)
+	}
+
+	if len(found) == 0 {
+		return Move{}, fmt.Errorf("illegal move %q", san)
+	}
+	if len(found) > 1 {
+		return Move{}, fmt.Errorf("ambiguous move %q matches %d legal moves", san, len(found))
+	}
+	return found[0], nil
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+// plays a sequence of SAN moves, failing the test on the first illegal one
+func playSAN(t *testing.T, game *ChessGame, sans ...string) {
+	t.Helper()
+	for _, san := range sans {
+		move, err := game.CurrentState.ParseSAN(san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		game.ExecuteMoveOnGame(move)
+	}
+}
+
+func TestSAN(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		// the move as written, and as MoveToSAN writes it if that differs
+		san       string
+		canonical string
+		uci       string
+	}{
+		{"pawn push", StartingFEN, "e4", "", "e2e4"},
+		{"knight", StartingFEN, "Nf3", "", "g1f3"},
+		{"file disambiguation", "4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", "Nbd2", "", "b1d2"},
+		{"rank disambiguation", "4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "R1a3", "", "a1a3"},
+		{"square disambiguation", "4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "Qa1b2", "", "a1b2"},
+		{"needless disambiguation", StartingFEN, "Ngf3", "Nf3", "g1f3"},
+		{"capture", "4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", "exd5", "", "e4d5"},
+		{"en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6", "", "e5d6"},
+		{"promotion with check", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8=Q+", "", "a7a8q"},
+		{"promotion without equals", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8Q", "a8=Q+", "a7a8q"},
+		{"underpromotion", "4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8=N", "", "a7a8n"},
+		{"capturing promotion", "1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1", "axb8=R+", "", "a7b8r"},
+		{"check", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", "Ra8+", "", "a1a8"},
+		{"checkmate", "rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq g3 0 2", "Qh4#", "", "d8h4"},
+		{"annotation", StartingFEN, "e4!?", "e4", "e2e4"},
+		{"short castling", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O", "", "e1g1"},
+		{"long castling", "r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "O-O-O", "", "e8c8"},
+		{"castling with zeros", "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0", "O-O", "e1g1"},
+	}
+	for _, test := range tests {
+		state, err := NewChessStateFromFEN(test.fen)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		move, err := state.ParseSAN(test.san)
+		if err != nil {
+			t.Errorf("%v: ParseSAN(%q): %v", test.name, test.san, err)
+			continue
+		}
+		if move.UCI() != test.uci {
+			t.Errorf("%v: ParseSAN(%q) = %v, want %v", test.name, test.san, move.UCI(), test.uci)
+		}
+		canonical := test.canonical
+		if canonical == "" {
+			canonical = test.san
+		}
+		if got := state.MoveToSAN(move); got != canonical {
+			t.Errorf("%v: MoveToSAN(%v) = %q, want %q", test.name, test.uci, got, canonical)
+		}
+	}
+}
+
+func TestParseSANErrors(t *testing.T) {
+	tests := []struct {
+		fen  string
+		san  string
+		want string
+	}{
+		{StartingFEN, "Zz9", "invalid SAN"},
+		{StartingFEN, "e5", "illegal move"},
+		{StartingFEN, "O-O", "castling is not possible"},
+		{"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", "Nd2", "ambiguous move"},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", "Kd2=Q", "only pawns can promote"},
+		// a pawn reaching the last rank must say what it promotes to
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a8", "illegal move"},
+	}
+	for _, test := range tests {
+		state, err := NewChessStateFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = state.ParseSAN(test.san)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("ParseSAN(%q) in %q = %v, want an error containing %q", test.san, test.fen, err, test.want)
+		}
+	}
+}
+
+func TestSANHistory(t *testing.T) {
+	game := NewChessGame()
+	sans := []string{"e4", "e6", "Nf3", "Nc6", "Bb5", "a6", "Bxc6", "Nf6", "O-O", "Bd6", "Bxd7+", "Kxd7"}
+	playSAN(t, &game, sans...)
+	if history := game.SANHistory(); strings.Join(history, " ") != strings.Join(sans, " ") {
+		t.Errorf("SANHistory() = %v, want %v", history, sans)
+	}
+	game.UndoLastMove()
+	if history := game.SANHistory(); strings.Join(history, " ") != strings.Join(sans[:len(sans)-1], " ") {
+		t.Errorf("SANHistory() after undo = %v, want %v", history, sans[:len(sans)-1])
+	}
+}
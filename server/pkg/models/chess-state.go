@@ -8,38 +8,85 @@ import (
 
 // records various information about the state of a chess position
 // current board
-// current turn as well as previous move played
+// current turn as well as the en passant target square
 // legality of castling for each side
+// halfmove clock and fullmove number
 type ChessState struct {
 	Board               *ChessBoard
 	Turn                int8
-	previousMove        Move
+	enPassantSquare     Location
 	whiteCanCastleShort bool
 	whiteCanCastleLong  bool
 	blackCanCastleShort bool
 	blackCanCastleLong  bool
+	halfmoveClock       int
+	fullmoveNumber      int
+	hash                uint64
+	// generator used by EnumerateMoves, carried over to every state reached from this one
+	generator MoveGenerator
 }
 
 // creates new game state
 func NewChessState() *ChessState {
-	return &ChessState{
+	state := &ChessState{
 		Board:               NewChessBoard(),
 		Turn:                White,
-		previousMove:        Move{},
+		enPassantSquare:     NoSquare,
 		whiteCanCastleShort: true,
 		whiteCanCastleLong:  true,
 		blackCanCastleShort: true,
 		blackCanCastleLong:  true,
+		halfmoveClock:       0,
+		fullmoveNumber:      1,
 	}
+	state.hash = state.ComputeHash()
+	return state
+}
+
+// returns the number of halfmoves since the last capture or pawn move
+func (state *ChessState) HalfmoveClock() int {
+	return state.halfmoveClock
+}
+
+// returns the number of the current full move, starting at 1 and incremented after black moves
+func (state *ChessState) FullmoveNumber() int {
+	return state.fullmoveNumber
+}
+
+// returns true if the side to move is in check
+func (state *ChessState) IsInCheck() bool {
+	if state.Turn == White {
+		return state.Board.IsWhiteInCheck()
+	}
+	return state.Board.IsBlackInCheck()
+}
+
+// returns a copy of the state that shares no board with the original
+func (state *ChessState) Copy() *ChessState {
+	copied := *state
+	board := *state.Board
+	copied.Board = &board
+	return &copied
+}
+
+// sets the generator EnumerateMoves uses for this state, the default is MailboxGenerator
+func (state *ChessState) SetMoveGenerator(generator MoveGenerator) {
+	state.generator = generator
 }
 
 // returns a slice of all legal moves for a ChessState object
 func (state *ChessState) EnumerateMoves() []Move {
+	return state.EnumerateMovesWith(state.generator)
+}
+
+// returns a slice of all legal moves for a ChessState object, using a specific move generator
+func (state *ChessState) EnumerateMovesWith(generator MoveGenerator) []Move {
+	if generator == BitboardGenerator && (state.Turn == White || state.Turn == Black) {
+		return state.enumerateMovesBitboard()
+	}
 	if state.Turn == White {
-		fmt.Println("Enumerating moves for white.")
 		return state.enumerateMovesWhite()
 	} else if state.Turn == Black {
-		fmt.Println("Enumerating moves for black.")
 		return state.enumerateMovesBlack()
 	} else {
 		fmt.Println("Trying to enumerate moves for state with invalid turn value.")
@@ -124,19 +171,17 @@ func (state *ChessState) enumerateMovesWhitePawn(moves []Move, i, j int) []Move
 			}
 		}
 		// check for en peasant
-		prev := state.previousMove
-		if prev.Type == Normal {
-			if prev.OldSquare.Row == 6 && prev.NewSquare.Row == 4 && state.Board[prev.NewSquare.Row][prev.NewSquare.Col] == BlackPawn {
-				if j-1 == prev.NewSquare.Col {
-					move := NewMove(EnPassant, i, j, i+1, j-1)
-					if state.isLegalMove(move) {
-						moves = append(moves, move)
-					}
-				} else if j+1 == prev.NewSquare.Col {
-					move := NewMove(EnPassant, i, j, i+1, j+1)
-					if state.isLegalMove(move) {
-						moves = append(moves, move)
-					}
+		target := state.enPassantSquare
+		if target.Row == i+1 {
+			if j-1 == target.Col {
+				move := NewMove(EnPassant, i, j, i+1, j-1)
+				if state.isLegalMove(move) {
+					moves = append(moves, move)
+				}
+			} else if j+1 == target.Col {
+				move := NewMove(EnPassant, i, j, i+1, j+1)
+				if state.isLegalMove(move) {
+					moves = append(moves, move)
 				}
 			}
 		}
@@ -587,8 +632,8 @@ func (state *ChessState) enumerateMovesWhiteKing(moves []Move, i, j int) []Move
 		if state.Board[0][1] == EmptySquare &&
 			state.Board[0][2] == EmptySquare &&
 			state.Board[0][3] == EmptySquare &&
-			state.Board.IsSquareAttackedByBlack(0, 3) &&
-			state.Board.IsSquareAttackedByBlack(0, 4) {
+			!state.Board.IsSquareAttackedByBlack(0, 3) &&
+			!state.Board.IsSquareAttackedByBlack(0, 4) {
 
 			move := NewMove(CastleLong, i, j, i, j-2)
 			if state.isLegalMove(move) {
@@ -627,7 +672,7 @@ func (state *ChessState) enumerateMovesBlack() []Move {
 }
 
 func (state *ChessState) enumerateMovesBlackPawn(moves []Move, i, j int) []Move {
-	if i == 2 {
+	if i == 6 {
 		// check for single move
 		if state.Board[i-1][j] == EmptySquare {
 			move := NewMove(Normal, i, j, i-1, j)
@@ -677,19 +722,17 @@ func (state *ChessState) enumerateMovesBlackPawn(moves []Move, i, j int) []Move
 			}
 		}
 		// check for en peasant
-		prev := state.previousMove
-		if prev.Type == Normal {
-			if prev.OldSquare.Row == 1 && prev.NewSquare.Row == 3 && state.Board[prev.NewSquare.Row][prev.NewSquare.Col] == WhitePawn {
-				if j-1 == prev.NewSquare.Col {
-					move := NewMove(EnPassant, i, j, i-1, j-1)
-					if state.isLegalMove(move) {
-						moves = append(moves, move)
-					}
-				} else if j+1 == prev.NewSquare.Col {
-					move := NewMove(EnPassant, i, j, i-1, j+1)
-					if state.isLegalMove(move) {
-						moves = append(moves, move)
-					}
+		target := state.enPassantSquare
+		if target.Row == i-1 {
+			if j-1 == target.Col {
+				move := NewMove(EnPassant, i, j, i-1, j-1)
+				if state.isLegalMove(move) {
+					moves = append(moves, move)
+				}
+			} else if j+1 == target.Col {
+				move := NewMove(EnPassant, i, j, i-1, j+1)
+				if state.isLegalMove(move) {
+					moves = append(moves, move)
 				}
 			}
 		}
@@ -740,7 +783,7 @@ func (state *ChessState) enumerateMovesBlackPawn(moves []Move, i, j int) []Move
 			}
 		}
 		if j+1 <= 7 && state.Board[i-1][j+1] > 0 {
-			move := NewMove(Normal, i, j, i+1, j-1)
+			move := NewMove(Normal, i, j, i-1, j+1)
 			if state.isLegalMove(move) {
 				moves = append(moves, move)
 			}
@@ -1140,8 +1183,8 @@ func (state *ChessState) enumerateMovesBlackKing(moves []Move, i, j int) []Move
 		if state.Board[7][1] == EmptySquare &&
 			state.Board[7][2] == EmptySquare &&
 			state.Board[7][3] == EmptySquare &&
-			state.Board.IsSquareAttackedByWhite(7, 3) &&
-			state.Board.IsSquareAttackedByWhite(7, 4) {
+			!state.Board.IsSquareAttackedByWhite(7, 3) &&
+			!state.Board.IsSquareAttackedByWhite(7, 4) {
 
 			move := NewMove(CastleLong, i, j, i, j-2)
 			if state.isLegalMove(move) {
@@ -1167,24 +1210,134 @@ func (state *ChessState) isLegalMove(move Move) bool {
 	}
 }
 
+// executes a move, leaving any board shared with other states untouched
 func (state *ChessState) ExecuteMoveOnState(move Move) *ChessState {
+	board := *state.Board
+	state.Board = &board
+	state.MakeMove(move)
+	return state
+}
+
+// records everything MakeMove changes that can't be recomputed from the move itself
+type MoveUndo struct {
+	captured            int8
+	whiteCanCastleShort bool
+	whiteCanCastleLong  bool
+	blackCanCastleShort bool
+	blackCanCastleLong  bool
+	enPassantSquare     Location
+	halfmoveClock       int
+	fullmoveNumber      int
+	hash                uint64
+}
+
+// executes a move in place, modifying the state's board
+// the returned MoveUndo restores the previous state when passed to UnmakeMove
+func (state *ChessState) MakeMove(move Move) MoveUndo {
+	undo := MoveUndo{
+		captured:            state.Board[move.NewSquare.Row][move.NewSquare.Col],
+		whiteCanCastleShort: state.whiteCanCastleShort,
+		whiteCanCastleLong:  state.whiteCanCastleLong,
+		blackCanCastleShort: state.blackCanCastleShort,
+		blackCanCastleLong:  state.blackCanCastleLong,
+		enPassantSquare:     state.enPassantSquare,
+		halfmoveClock:       state.halfmoveClock,
+		fullmoveNumber:      state.fullmoveNumber,
+		hash:                state.hash,
+	}
+	if move.Type == EnPassant {
+		undo.captured = state.Board[move.OldSquare.Row][move.NewSquare.Col]
+	}
+
+	movingPiece := state.Board[move.OldSquare.Row][move.OldSquare.Col]
+	isCapture := undo.captured != EmptySquare && move.Type != CastleShort && move.Type != CastleLong
+
+	// remove the old castling rights and en passant keys now, the new ones are added once they are known
+	hash := state.hash ^ state.zobristMoveDelta(move) ^ state.zobristCastlingRights() ^ state.zobristEnPassantTarget() ^ zobristBlackToMove
 
 	newBoard, wCastleShort, wCastleLong, bCastleShort, bCastleLong := executeMoveOnBoard(move, *state.Board)
 
-	state.Board = &newBoard
+	*state.Board = newBoard
 	state.whiteCanCastleShort = state.whiteCanCastleShort && wCastleShort
 	state.whiteCanCastleLong = state.whiteCanCastleLong && wCastleLong
 	state.blackCanCastleShort = state.blackCanCastleShort && bCastleShort
-	state.blackCanCastleLong = state.whiteCanCastleLong && bCastleLong
+	state.blackCanCastleLong = state.blackCanCastleLong && bCastleLong
+
+	// a double pawn push leaves the skipped square as the en passant target
+	state.enPassantSquare = NoSquare
+	if (movingPiece == WhitePawn || movingPiece == BlackPawn) &&
+		(move.NewSquare.Row-move.OldSquare.Row == 2 || move.OldSquare.Row-move.NewSquare.Row == 2) {
+		state.enPassantSquare = Location{
+			Row: (move.OldSquare.Row + move.NewSquare.Row) / 2,
+			Col: move.OldSquare.Col,
+		}
+	}
+
+	// pawn moves and captures reset the halfmove clock
+	if movingPiece == WhitePawn || movingPiece == BlackPawn || isCapture {
+		state.halfmoveClock = 0
+	} else {
+		state.halfmoveClock++
+	}
 
-	state.previousMove = move
 	if state.Turn == White {
 		state.Turn = Black
 	} else if state.Turn == Black {
 		state.Turn = White
+		state.fullmoveNumber++
 	} else {
 		fmt.Println("What is even going on???")
 	}
 
-	return state
+	state.hash = hash ^ state.zobristCastlingRights() ^ state.zobristEnPassantTarget()
+
+	return undo
+}
+
+// takes back a move made with MakeMove, restoring the state exactly
+// moves must be unmade in the reverse order they were made
+func (state *ChessState) UnmakeMove(move Move, undo MoveUndo) {
+	if state.Turn == White {
+		state.Turn = Black
+	} else {
+		state.Turn = White
+	}
+
+	board := state.Board
+	old, new := move.OldSquare, move.NewSquare
+	switch move.Type {
+	case CastleShort:
+		board[old.Row][4] = board[old.Row][6]
+		board[old.Row][7] = board[old.Row][5]
+		board[old.Row][5] = EmptySquare
+		board[old.Row][6] = EmptySquare
+	case CastleLong:
+		board[old.Row][4] = board[old.Row][2]
+		board[old.Row][0] = board[old.Row][3]
+		board[old.Row][2] = EmptySquare
+		board[old.Row][3] = EmptySquare
+	case EnPassant:
+		board[old.Row][old.Col] = board[new.Row][new.Col]
+		board[new.Row][new.Col] = EmptySquare
+		board[old.Row][new.Col] = undo.captured
+	case PromoteQueen, PromoteRook, PromoteBishop, PromoteKnight:
+		if state.Turn == White {
+			board[old.Row][old.Col] = WhitePawn
+		} else {
+			board[old.Row][old.Col] = BlackPawn
+		}
+		board[new.Row][new.Col] = undo.captured
+	default:
+		board[old.Row][old.Col] = board[new.Row][new.Col]
+		board[new.Row][new.Col] = undo.captured
+	}
+
+	state.whiteCanCastleShort = undo.whiteCanCastleShort
+	state.whiteCanCastleLong = undo.whiteCanCastleLong
+	state.blackCanCastleShort = undo.blackCanCastleShort
+	state.blackCanCastleLong = undo.blackCanCastleLong
+	state.enPassantSquare = undo.enPassantSquare
+	state.halfmoveClock = undo.halfmoveClock
+	state.fullmoveNumber = undo.fullmoveNumber
+	state.hash = undo.hash
 }
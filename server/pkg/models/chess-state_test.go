@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+)
+
+// walks the move tree checking that every UnmakeMove restores the exact position
+func checkUnmake(t *testing.T, state *ChessState, depth int) {
+	if depth == 0 {
+		return
+	}
+	before := state.FEN()
+	board := *state.Board
+	for _, move := range state.EnumerateMoves() {
+		undo := state.MakeMove(move)
+		checkUnmake(t, state, depth-1)
+		state.UnmakeMove(move, undo)
+		if after := state.FEN(); after != before || *state.Board != board {
+			t.Fatalf("unmaking %s from %q left %q", move.UCI(), before, after)
+		}
+	}
+}
+
+func TestUnmakeMoveRestoresState(t *testing.T) {
+	for _, position := range perftPositions {
+		state, err := NewChessStateFromFEN(position.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", position.name, err)
+		}
+		checkUnmake(t, state, 3)
+	}
+}
@@ -0,0 +1,169 @@
+package models
+
+// random keys for Zobrist hashing
+// a position's hash is the XOR of the keys for every piece on its square, the side to move,
+// each castling right held and the en passant file (when an en passant capture is available)
+var (
+	zobristPieces      [12][64]uint64
+	zobristBlackToMove uint64
+	zobristCastling    [4]uint64
+	zobristEnPassant   [8]uint64
+)
+
+// indexes into zobristCastling
+const (
+	whiteShortCastlingKey = iota
+	whiteLongCastlingKey
+	blackShortCastlingKey
+	blackLongCastlingKey
+)
+
+func init() {
+	// fixed seed so hashes are stable between runs (e.g. for stored opening books)
+	seed := uint64(0x9E3779B97F4A7C15)
+	next := func() uint64 {
+		// splitmix64
+		seed += 0x9E3779B97F4A7C15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		return z ^ (z >> 31)
+	}
+
+	for piece := range zobristPieces {
+		for square := range zobristPieces[piece] {
+			zobristPieces[piece][square] = next()
+		}
+	}
+	zobristBlackToMove = next()
+	for i := range zobristCastling {
+		zobristCastling[i] = next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = next()
+	}
+}
+
+// returns the key for a piece standing on a square
+func zobristPiece(piece int8, row, col int) uint64 {
+	index := int(piece) - 1
+	if piece < 0 {
+		index = int(-piece) + 5
+	}
+	return zobristPieces[index][row*8+col]
+}
+
+// returns the combined key for the castling rights currently held
+func (state *ChessState) zobristCastlingRights() uint64 {
+	var key uint64
+	if state.whiteCanCastleShort {
+		key ^= zobristCastling[whiteShortCastlingKey]
+	}
+	if state.whiteCanCastleLong {
+		key ^= zobristCastling[whiteLongCastlingKey]
+	}
+	if state.blackCanCastleShort {
+		key ^= zobristCastling[blackShortCastlingKey]
+	}
+	if state.blackCanCastleLong {
+		key ^= zobristCastling[blackLongCastlingKey]
+	}
+	return key
+}
+
+// returns the key for the en passant target, or 0 if the side to move has no legal en passant capture
+// ignoring unusable targets keeps positions that only differ by an irrelevant target equal
+func (state *ChessState) zobristEnPassantTarget() uint64 {
+	target := state.enPassantSquare
+	if target == NoSquare {
+		return 0
+	}
+	// the pawn that just moved stands one rank past the target, from the mover's point of view
+	row, pawn := target.Row-1, int8(WhitePawn)
+	if state.Turn == Black {
+		row, pawn = target.Row+1, BlackPawn
+	}
+	for _, col := range [2]int{target.Col - 1, target.Col + 1} {
+		if col < 0 || col > 7 || state.Board[row][col] != pawn {
+			continue
+		}
+		// a pinned pawn, or one that would leave the king in check, can't take
+		if state.isLegalMove(NewMove(EnPassant, row, col, target.Row, target.Col)) {
+			return zobristEnPassant[target.Col]
+		}
+	}
+	return 0
+}
+
+// returns the Zobrist hash of the position, maintained incrementally as moves are made
+func (state *ChessState) Hash() uint64 {
+	return state.hash
+}
+
+// recomputes the Zobrist hash from scratch
+// Hash() should always equal this, the recomputation exists to verify the incremental updates
+func (state *ChessState) ComputeHash() uint64 {
+	var hash uint64
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			if state.Board[i][j] != EmptySquare {
+				hash ^= zobristPiece(state.Board[i][j], i, j)
+			}
+		}
+	}
+	if state.Turn == Black {
+		hash ^= zobristBlackToMove
+	}
+	hash ^= state.zobristCastlingRights()
+	hash ^= state.zobristEnPassantTarget()
+	return hash
+}
+
+// returns the hash changes caused by the pieces a move relocates, adds or removes
+// must be called before the move is made
+func (state *ChessState) zobristMoveDelta(move Move) uint64 {
+	board := state.Board
+	old, new := move.OldSquare, move.NewSquare
+	piece := board[old.Row][old.Col]
+
+	delta := zobristPiece(piece, old.Row, old.Col)
+	switch move.Type {
+	case CastleShort:
+		rook := board[old.Row][7]
+		delta ^= zobristPiece(piece, old.Row, 6) ^ zobristPiece(rook, old.Row, 7) ^ zobristPiece(rook, old.Row, 5)
+	case CastleLong:
+		rook := board[old.Row][0]
+		delta ^= zobristPiece(piece, old.Row, 2) ^ zobristPiece(rook, old.Row, 0) ^ zobristPiece(rook, old.Row, 3)
+	case EnPassant:
+		delta ^= zobristPiece(piece, new.Row, new.Col) ^ zobristPiece(board[old.Row][new.Col], old.Row, new.Col)
+	default:
+		placed := piece
+		if move.Type >= PromoteQueen {
+			placed = promotedPiece(move.Type, piece > 0)
+		}
+		delta ^= zobristPiece(placed, new.Row, new.Col)
+		if captured := board[new.Row][new.Col]; captured != EmptySquare {
+			delta ^= zobristPiece(captured, new.Row, new.Col)
+		}
+	}
+	return delta
+}
+
+// returns the piece a promotion move creates
+func promotedPiece(moveType MoveType, white bool) int8 {
+	var piece int8
+	switch moveType {
+	case PromoteQueen:
+		piece = WhiteQueen
+	case PromoteRook:
+		piece = WhiteRook
+	case PromoteBishop:
+		piece = WhiteBishop
+	case PromoteKnight:
+		piece = WhiteKnight
+	}
+	if !white {
+		piece = -piece
+	}
+	return piece
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+)
+
+// walks the move tree checking the incremental hash against a full recomputation after every make and unmake
+func checkHash(t *testing.T, state *ChessState, depth int) {
+	if depth == 0 {
+		return
+	}
+	for _, move := range state.EnumerateMoves() {
+		before := state.Hash()
+		undo := state.MakeMove(move)
+		if state.Hash() != state.ComputeHash() {
+			t.Fatalf("hash drifted after %s, reaching %q", move.UCI(), state.FEN())
+		}
+		checkHash(t, state, depth-1)
+		state.UnmakeMove(move, undo)
+		if state.Hash() != before {
+			t.Fatalf("hash not restored after unmaking %s in %q", move.UCI(), state.FEN())
+		}
+	}
+}
+
+func TestIncrementalHashMatchesRecomputation(t *testing.T) {
+	for _, position := range perftPositions {
+		state, err := NewChessStateFromFEN(position.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", position.name, err)
+		}
+		checkHash(t, state, 3)
+	}
+	for _, position := range perftEdgeCases {
+		state, err := NewChessStateFromFEN(position.fen)
+		if err != nil {
+			t.Fatalf("%s: %v", position.name, err)
+		}
+		checkHash(t, state, 3)
+	}
+}
+
+func TestHashTranspositions(t *testing.T) {
+	first := NewChessGame()
+	playSAN(t, &first, "e4", "e5", "Nf3", "Nc6")
+	second := NewChessGame()
+	playSAN(t, &second, "Nf3", "Nc6", "e4", "e5")
+	if first.CurrentState.Hash() != second.CurrentState.Hash() {
+		t.Error("transposed positions have different hashes, even though the e6 target can't be used")
+	}
+
+	fromFEN, err := NewChessStateFromFEN(first.CurrentState.FEN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromFEN.Hash() != first.CurrentState.Hash() {
+		t.Error("position loaded from FEN has a different hash to the played position")
+	}
+}
+
+func TestHashUsableEnPassant(t *testing.T) {
+	withTarget, err := NewChessStateFromFEN("4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutTarget, err := NewChessStateFromFEN("4k3/8/8/3pP3/8/8/8/4K3 w - - 0 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withTarget.Hash() == withoutTarget.Hash() {
+		t.Error("a usable en passant target doesn't change the hash")
+	}
+}
@@ -0,0 +1,75 @@
+package opponent
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/engine"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	"github.com/BrianJHenry/go-chess/server/pkg/transposition"
+)
+
+// transposition table shared by every built-in engine opponent
+var engineTable = transposition.New(64)
+
+// chooses the moves of the computer side of a game
+type Opponent interface {
+	// name shown to players and in exported games
+	Name() string
+	// returns the move to play in the game's current position, which must have at least one possible move
+	Move(game *models.ChessGame) (models.Move, error)
+	// releases the opponent's resources, it must not be used afterwards
+	Close() error
+}
+
+// the built-in engine playing at a difficulty level
+type Engine struct {
+	engine *engine.Engine
+	level  engine.Level
+	rng    *rand.Rand
+}
+
+// creates a built-in engine opponent
+func NewEngine(level engine.Level) *Engine {
+	opponent := &Engine{
+		engine: engine.New(),
+		level:  level,
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	opponent.engine.Table = engineTable
+	return opponent
+}
+
+func (opponent *Engine) Name() string {
+	return "Computer"
+}
+
+func (opponent *Engine) Move(game *models.ChessGame) (models.Move, error) {
+	result := opponent.engine.ChooseMove(game.CurrentState, game.PositionHistory(), opponent.level, opponent.rng)
+	return result.BestMove, nil
+}
+
+func (opponent *Engine) Close() error {
+	return nil
+}
+
+// plays a uniformly random legal move
+type Random struct {
+	rng *rand.Rand
+}
+
+func NewRandom() *Random {
+	return &Random{rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (opponent *Random) Name() string {
+	return "Random"
+}
+
+func (opponent *Random) Move(game *models.ChessGame) (models.Move, error) {
+	return game.PossibleMoves[opponent.rng.Intn(len(game.PossibleMoves))], nil
+}
+
+func (opponent *Random) Close() error {
+	return nil
+}
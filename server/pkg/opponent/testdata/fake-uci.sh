@@ -0,0 +1,42 @@
+#!/bin/sh
+# stand-in for a UCI engine, the first argument picks its behaviour:
+#   good    answers every go with the move in $FAKE_BESTMOVE (default e2e4)
+#   slow    answers go only after stop
+#   hang    never answers go, not even after stop
+#   flood   answers go with endless info lines and never a move
+#   crash   exits when asked to go
+#   illegal answers every go with e2e5
+#   mute    never finishes the handshake
+mode=${1:-good}
+while read -r line; do
+	case "$line" in
+	uci)
+		[ "$mode" = mute ] && continue
+		echo "id name fake"
+		echo "uciok"
+		;;
+	isready)
+		echo "readyok"
+		;;
+	position*)
+		echo "$line" >> "${FAKE_LOG:-/dev/null}"
+		;;
+	go*)
+		case "$mode" in
+		good)
+			echo "info depth 1 score cp 0"
+			echo "bestmove ${FAKE_BESTMOVE:-e2e4}"
+			;;
+		crash) exit 1 ;;
+		illegal) echo "bestmove e2e5" ;;
+		flood) while :; do echo "info depth 1 score cp 0"; done ;;
+		esac
+		;;
+	stop)
+		[ "$mode" = slow ] && echo "bestmove ${FAKE_BESTMOVE:-e2e4}"
+		;;
+	quit)
+		exit 0
+		;;
+	esac
+done
@@ -0,0 +1,252 @@
+package opponent
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+const (
+	defaultUCIMoveTime = time.Second
+	defaultUCITimeout  = 5 * time.Second
+	// how long the engine has to answer stop or quit before it is killed
+	uciGracePeriod = time.Second
+)
+
+// settings for an external UCI engine
+type UCIConfig struct {
+	// engine binary and its arguments
+	Path string
+	Args []string
+	// thinking time per move, sent as go movetime
+	MoveTime time.Duration
+	// how much longer than MoveTime the engine may take to answer, and how long the handshake may take
+	Timeout time.Duration
+	// sent as setoption commands after the handshake, e.g. "Skill Level": "5"
+	Options map[string]string
+}
+
+// an external engine process spoken to over the Universal Chess Interface
+type UCIEngine struct {
+	config UCIConfig
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	// lines written by the engine, closed when its output ends
+	lines chan string
+	// closed by Close, after which the engine's output is read and thrown away
+	done chan struct{}
+	// closed when the goroutine reading the engine's output returns
+	readerExited chan struct{}
+	// as sent by the engine in its id during the handshake
+	name string
+	// set once the engine has failed, every later move returns it
+	err error
+}
+
+// starts the engine and completes the UCI handshake
+func NewUCIEngine(config UCIConfig) (*UCIEngine, error) {
+	if config.MoveTime <= 0 {
+		config.MoveTime = defaultUCIMoveTime
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultUCITimeout
+	}
+
+	cmd := exec.Command(config.Path, config.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting uci engine %q: %w", config.Path, err)
+	}
+
+	opponent := &UCIEngine{
+		config:       config,
+		cmd:          cmd,
+		stdin:        stdin,
+		lines:        make(chan string, 64),
+		done:         make(chan struct{}),
+		readerExited: make(chan struct{}),
+	}
+	go func() {
+		defer close(opponent.readerExited)
+		defer close(opponent.lines)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			select {
+			case opponent.lines <- scanner.Text():
+			case <-opponent.done:
+				// nobody reads lines after Close, keep draining so the engine is never blocked writing
+			}
+		}
+	}()
+
+	if err := opponent.handshake(); err != nil {
+		close(opponent.done)
+		opponent.kill()
+		opponent.cmd.Wait()
+		<-opponent.readerExited
+		return nil, fmt.Errorf("uci engine %q: %w", config.Path, err)
+	}
+	return opponent, nil
+}
+
+func (opponent *UCIEngine) handshake() error {
+	if err := opponent.send("uci"); err != nil {
+		return err
+	}
+	if _, err := opponent.waitFor("uciok", opponent.config.Timeout); err != nil {
+		return err
+	}
+	for name, value := range opponent.config.Options {
+		if err := opponent.send(fmt.Sprintf("setoption name %s value %s", name, value)); err != nil {
+			return err
+		}
+	}
+	if err := opponent.send("ucinewgame"); err != nil {
+		return err
+	}
+	return opponent.ready()
+}
+
+func (opponent *UCIEngine) ready() error {
+	if err := opponent.send("isready"); err != nil {
+		return err
+	}
+	_, err := opponent.waitFor("readyok", opponent.config.Timeout)
+	return err
+}
+
+// returns the name the engine reported, or its binary's name if it sent none
+func (opponent *UCIEngine) Name() string {
+	if opponent.name != "" {
+		return opponent.name
+	}
+	return filepath.Base(opponent.config.Path)
+}
+
+// sends the game's moves and asks the engine for its move
+// an engine that does not answer in time is told to stop, and killed if it does not answer that either
+// after a crash or kill every call returns the same error
+func (opponent *UCIEngine) Move(game *models.ChessGame) (models.Move, error) {
+	if opponent.err != nil {
+		return models.Move{}, opponent.err
+	}
+
+	var position strings.Builder
+	if game.StartingFEN == models.StartingFEN {
+		position.WriteString("position startpos")
+	} else {
+		position.WriteString("position fen " + game.StartingFEN)
+	}
+	if len(game.MoveHistory) > 0 {
+		position.WriteString(" moves")
+		for _, move := range game.MoveHistory {
+			position.WriteString(" " + move.UCI())
+		}
+	}
+
+	if err := opponent.send(position.String()); err != nil {
+		return models.Move{}, opponent.fail(err)
+	}
+	if err := opponent.send(fmt.Sprintf("go movetime %d", opponent.config.MoveTime.Milliseconds())); err != nil {
+		return models.Move{}, opponent.fail(err)
+	}
+
+	line, err := opponent.waitFor("bestmove", opponent.config.MoveTime+opponent.config.Timeout)
+	if errors.Is(err, errTimeout) {
+		opponent.send("stop")
+		line, err = opponent.waitFor("bestmove", uciGracePeriod)
+	}
+	if err != nil {
+		opponent.kill()
+		return models.Move{}, opponent.fail(err)
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return models.Move{}, opponent.fail(fmt.Errorf("uci engine sent %q without a move", line))
+	}
+	move, err := game.CurrentState.ParseUCI(fields[1])
+	if err != nil {
+		// an engine that disagrees about the rules cannot be trusted with later moves either
+		opponent.kill()
+		return models.Move{}, opponent.fail(fmt.Errorf("uci engine played %q: %w", fields[1], err))
+	}
+	return move, nil
+}
+
+// asks the engine to quit, killing it if it does not, and waits for its output to be read to the end
+func (opponent *UCIEngine) Close() error {
+	if opponent.err == nil {
+		opponent.send("quit")
+	}
+	close(opponent.done)
+	exited := make(chan error, 1)
+	go func() {
+		exited <- opponent.cmd.Wait()
+	}()
+	select {
+	case <-exited:
+	case <-time.After(uciGracePeriod):
+		opponent.cmd.Process.Kill()
+		<-exited
+	}
+	<-opponent.readerExited
+	if opponent.err == nil {
+		opponent.err = fmt.Errorf("uci engine %q is closed", opponent.config.Path)
+	}
+	return nil
+}
+
+var errTimeout = errors.New("timed out")
+
+// reads lines until one starting with prefix, skipping info and other output
+func (opponent *UCIEngine) waitFor(prefix string, timeout time.Duration) (string, error) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case line, ok := <-opponent.lines:
+			if !ok {
+				return "", fmt.Errorf("uci engine %q exited while waiting for %s", opponent.config.Path, prefix)
+			}
+			if line == prefix || strings.HasPrefix(line, prefix+" ") {
+				return line, nil
+			}
+			if strings.HasPrefix(line, "id name ") {
+				opponent.name = strings.TrimSpace(strings.TrimPrefix(line, "id name "))
+			}
+		case <-deadline:
+			return "", fmt.Errorf("waiting for %s: %w", prefix, errTimeout)
+		}
+	}
+}
+
+func (opponent *UCIEngine) send(command string) error {
+	_, err := io.WriteString(opponent.stdin, command+"\n")
+	return err
+}
+
+// records the first failure so later moves report it
+func (opponent *UCIEngine) fail(err error) error {
+	if opponent.err == nil {
+		opponent.err = fmt.Errorf("uci engine %q: %w", opponent.config.Path, err)
+	}
+	return opponent.err
+}
+
+func (opponent *UCIEngine) kill() {
+	opponent.cmd.Process.Kill()
+}
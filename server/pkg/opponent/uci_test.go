@@ -0,0 +1,159 @@
+package opponent
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/engine"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+func fakeEngine(t *testing.T, mode string) UCIConfig {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake engine is a shell script")
+	}
+	return UCIConfig{
+		Path:     "testdata/fake-uci.sh",
+		Args:     []string{mode},
+		MoveTime: 10 * time.Millisecond,
+		Timeout:  200 * time.Millisecond,
+	}
+}
+
+func playUCI(t *testing.T, game *models.ChessGame, moves ...string) {
+	t.Helper()
+	for _, uci := range moves {
+		move, err := game.CurrentState.ParseUCI(uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		game.ExecuteMoveOnGame(move)
+	}
+}
+
+func TestUCIEngineMove(t *testing.T) {
+	config := fakeEngine(t, "good")
+	log := filepath.Join(t.TempDir(), "positions")
+	t.Setenv("FAKE_LOG", log)
+	t.Setenv("FAKE_BESTMOVE", "g8f6")
+
+	opponent, err := NewUCIEngine(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer opponent.Close()
+	if opponent.Name() != "fake" {
+		t.Errorf("name = %q, want the id sent by the engine", opponent.Name())
+	}
+
+	game := models.NewChessGame()
+	playUCI(t, &game, "e2e4")
+	move, err := opponent.Move(&game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if move.UCI() != "g8f6" || !game.IsPossibleMove(move) {
+		t.Errorf("got move %s", move.UCI())
+	}
+
+	fenGame, err := models.NewChessGameFromFEN("4k1n1/8/8/8/8/8/4P3/4K3 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opponent.Move(&fenGame); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "position startpos moves e2e4\nposition fen 4k1n1/8/8/8/8/8/4P3/4K3 b - - 0 1\n"
+	if string(contents) != want {
+		t.Errorf("engine was sent\n%s\nwant\n%s", contents, want)
+	}
+}
+
+func TestUCIEngineAnswersAfterStop(t *testing.T) {
+	opponent, err := NewUCIEngine(fakeEngine(t, "slow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer opponent.Close()
+
+	game := models.NewChessGame()
+	if move, err := opponent.Move(&game); err != nil || move.UCI() != "e2e4" {
+		t.Errorf("got %s, %v", move.UCI(), err)
+	}
+}
+
+func TestUCIEngineFailures(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"hang", "timed out"},
+		{"crash", "exited"},
+		{"illegal", "e2e5"},
+	}
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			opponent, err := NewUCIEngine(fakeEngine(t, test.mode))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer opponent.Close()
+
+			game := models.NewChessGame()
+			_, err = opponent.Move(&game)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got error %v, want one mentioning %q", err, test.want)
+			}
+			// the engine stays failed
+			if _, again := opponent.Move(&game); again != err {
+				t.Errorf("second move returned %v", again)
+			}
+		})
+	}
+}
+
+func TestUCIEngineCloseStopsReader(t *testing.T) {
+	opponent, err := NewUCIEngine(fakeEngine(t, "flood"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := models.NewChessGame()
+	if _, err := opponent.Move(&game); err == nil {
+		t.Fatal("flooding engine returned a move")
+	}
+	opponent.Close()
+	select {
+	case <-opponent.readerExited:
+	default:
+		t.Error("output reader still running after Close")
+	}
+}
+
+func TestUCIEngineStartFailures(t *testing.T) {
+	if _, err := NewUCIEngine(fakeEngine(t, "mute")); err == nil || !strings.Contains(err.Error(), "uciok") {
+		t.Errorf("engine without uciok gave %v", err)
+	}
+	if _, err := NewUCIEngine(UCIConfig{Path: "testdata/does-not-exist"}); err == nil {
+		t.Error("missing binary started")
+	}
+}
+
+func TestBuiltInOpponents(t *testing.T) {
+	for _, opponent := range []Opponent{NewEngine(engine.Levels[0]), NewRandom()} {
+		game := models.NewChessGame()
+		move, err := opponent.Move(&game)
+		if err != nil || !game.IsPossibleMove(move) {
+			t.Errorf("%s: got %s, %v", opponent.Name(), move.UCI(), err)
+		}
+	}
+}
@@ -2,30 +2,109 @@ package sockets
 
 import (
 	"log"
+	"sync"
+	"time"
 
 	"github.com/gofiber/contrib/websocket"
 )
 
+// messages queued for a client before it is considered too slow and disconnected
+const sendBufferSize = 64
+
 type Client struct {
 	Conn *websocket.Conn
 	Game *Game
+	// the authenticated user playing through this client
+	Username string
+	// token from an earlier connection, for taking back its seat
+	SeatToken string
+	// assigned by the game when the client joins, SpectatorSeat if they are watching
+	Seat int
+
+	// answers Game.Join
+	joined chan bool
+
+	// messages waiting to be written by the client's writer
+	send chan Message
+	// closed when Read returns, which stops the writer
+	done chan struct{}
+	// closed when the writer has returned
+	writerDone chan struct{}
+	closeOnce  sync.Once
+}
+
+func NewClient(conn *websocket.Conn, game *Game) *Client {
+	return &Client{
+		Conn:       conn,
+		Game:       game,
+		joined:     make(chan bool, 1),
+		send:       make(chan Message, sendBufferSize),
+		done:       make(chan struct{}),
+		writerDone: make(chan struct{}),
+	}
+}
+
+// queues a message for the client's writer without blocking, so the game loop never waits on a slow connection
+// a client that falls too far behind is disconnected
+func (c *Client) Send(message Message) {
+	select {
+	case c.send <- message:
+	default:
+		log.Printf("Client %q is not keeping up, disconnecting.", c.Username)
+		c.close()
+	}
+}
+
+// writes queued messages to the connection until Read returns
+func (c *Client) write() {
+	defer close(c.writerDone)
+	for {
+		select {
+		case message := <-c.send:
+			if err := c.Conn.WriteJSON(message); err != nil {
+				log.Println(err)
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
 }
 
+// closes the connection, which makes Read return
+// closing a hijacked connection is left to fasthttp once the handler returns, so the read is also cut short
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		c.Conn.Close()
+		c.Conn.SetReadDeadline(time.Now())
+	})
+}
+
+// reads moves from the connection until it closes, writing messages sent to the client meanwhile
 func (c *Client) Read() {
+	go c.write()
 	defer func() {
-		c.Conn.Close()
-		c.Game.Unregister <- c
+		c.close()
+		close(c.done)
+		<-c.writerDone
+		select {
+		case c.Game.Unregister <- c:
+		case <-c.Game.done:
+		}
 	}()
 
 	var err error
 	var move = &APIMove{}
 	log.Println("Begin read")
 	for {
+		// reset so fields missing from this message don't carry over from the last one
+		*move = APIMove{}
 		err = c.Conn.ReadJSON(move)
 		if err != nil {
 			log.Println(err)
 			return
 		}
-		c.Game.RecieveMove <- *move
+		c.Game.submit(SeatMove{Seat: c.Seat, Client: c, Move: *move})
 	}
 }
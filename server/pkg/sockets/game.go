@@ -1,10 +1,14 @@
 package sockets
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
-	"math/rand"
+	"sync"
+	"time"
 
+	"github.com/BrianJHenry/go-chess/server/pkg/clock"
 	"github.com/BrianJHenry/go-chess/server/pkg/models"
 )
 
@@ -26,6 +30,12 @@ type APIMove struct {
 	MoveType  string `json:"moveType"`
 	OldSquare int    `json:"oldSquare"`
 	NewSquare int    `json:"newSquare"`
+	// Standard Algebraic Notation, filled in for previous moves
+	// clients may send a move as SAN alone instead of type and squares
+	SAN string `json:"san,omitempty"`
+	// UCI long algebraic notation (e.g. "e7e8q"), filled in for every move
+	// clients may send a move as UCI alone instead of type and squares
+	UCI string `json:"uci,omitempty"`
 }
 
 func convertToAPIMove(move models.Move) APIMove {
@@ -37,6 +47,7 @@ func convertToAPIMove(move models.Move) APIMove {
 		MoveType:  moveType,
 		OldSquare: oldSquare,
 		NewSquare: newSquare,
+		UCI:       move.UCI(),
 	}
 }
 
@@ -64,6 +75,39 @@ type APIState struct {
 	Board         []int8    `json:"board"`
 	PreviousMoves []APIMove `json:"previousMoves"`
 	PossibleMoves []APIMove `json:"possibleMoves"`
+	// left out of untimed games
+	Clock *APIClock `json:"clock,omitempty"`
+	// number of clients watching the game
+	Spectators int `json:"spectators"`
+}
+
+// setup clocks to be sent across websockets
+type APIClock struct {
+	// milliseconds left for each side
+	White int64 `json:"white"`
+	Black int64 `json:"black"`
+	// side whose time is running: "white", "black" or "" when the clock is stopped
+	Running     string `json:"running"`
+	TimeControl string `json:"timeControl"`
+}
+
+func convertToAPIClock(gameClock *clock.Clock) *APIClock {
+	if gameClock == nil {
+		return nil
+	}
+	running := ""
+	switch gameClock.Running() {
+	case WhiteSeat:
+		running = "white"
+	case BlackSeat:
+		running = "black"
+	}
+	return &APIClock{
+		White:       gameClock.Remaining(WhiteSeat).Milliseconds(),
+		Black:       gameClock.Remaining(BlackSeat).Milliseconds(),
+		Running:     running,
+		TimeControl: gameClock.TimeControl().String(),
+	}
 }
 
 func convertToAPIState(game models.ChessGame, ownColor int) APIState {
@@ -84,9 +128,13 @@ func convertToAPIState(game models.ChessGame, ownColor int) APIState {
 		turn = false
 		possibleMoves = make([]APIMove, 0)
 	}
+	sanHistory := game.SANHistory()
 	var previousMoves = make([]APIMove, 0, len(game.MoveHistory))
-	for _, move := range game.MoveHistory {
+	for i, move := range game.MoveHistory {
 		convertedMove := convertToAPIMove(move)
+		if i < len(sanHistory) {
+			convertedMove.SAN = sanHistory[i]
+		}
 		previousMoves = append(previousMoves, convertedMove)
 	}
 
@@ -120,185 +168,683 @@ func CreateEmptyGameState() APIState {
 	}
 }
 
+// how long a disconnected player's seat is held by default
+const DefaultReconnectGrace = 30 * time.Second
+
+// how long a game waits by default for its seats to be filled before it is aborted
+const DefaultStartTimeout = 10 * time.Minute
+
+// records a game as it is played, e.g. in storage
+// the game loop calls it from its own goroutine
+type Recorder interface {
+	// records a move along with the time each side has left after it, zero in untimed games
+	RecordMove(gameID string, move models.Move, remaining [2]time.Duration) error
+	RecordResult(gameID string, result models.Result) error
+}
+
+// progress of a game, reported to Game.OnEvent
+type GameEvent int
+
+const (
+	GameStarted GameEvent = iota
+	GameMoved
+	GameEnded
+)
+
 // actual game logic
+// a game is two players and a ChessGame, seats without a player are filled by clients as they join
 type Game struct {
 	GameID string
-	Delete func(id string)
+	// called from the game loop once the game has ended and its players have been closed, nil if nothing needs doing
+	OnFinish func(id string)
 
 	// game info
-	NumberOfPlayers int
-
-	Clients []*Client
+	StartTime   time.Time
+	TimeControl clock.TimeControl
+	// players given when the game was created, nil for seats filled by clients
+	Players [2]Player
+	// usernames of the clients each open seat is kept for, "" if anyone may take it
+	// must be set before the game starts
+	Reserved [2]string
+	// records moves and the result, nil if the game is not recorded
+	// must be set before the game starts
+	Recorder Recorder
+	// how long the seat of a player who disconnects is held for them to reconnect before they lose
+	// must be set before the game starts
+	ReconnectGrace time.Duration
+	// how long a game with no moves waits for every seat to be filled before it ends as aborted, 0 to wait forever
+	// restored games already in progress carry on straight away, giving missing players ReconnectGrace to come back
+	// must be set before the game starts
+	StartTimeout time.Duration
+	// told when the game starts, after each move and when it ends, nil if nothing is listening
+	// it is called from the game loop so must not block, and must be set before the game starts
+	OnEvent func(event GameEvent, chessGame models.ChessGame)
 
 	// websocket handling
 	Register    chan *Client
 	Unregister  chan *Client
-	RecieveMove chan APIMove
+	RecieveMove chan SeatMove
+
+	// requests for a copy of the chess game from outside the game loop
+	Snapshot chan chan models.ChessGame
+	// messages for every player and spectator from outside the game loop
+	announcements chan string
+
+	// players in each seat, only used by the game loop
+	seats [2]Player
+	// clients watching the game, only used by the game loop
+	spectators map[*Client]bool
+	// position to continue from instead of the starting position, set by Restore
+	restored *models.ChessGame
+
+	// set once both seats have been filled and the game has begun, only used by the game loop
+	started bool
+	// fires when the game has waited StartTimeout without beginning
+	startTimer *time.Timer
+	// tokens for rejoining each seat, only used by the game loop
+	seatTokens [2]string
+	// when the player in each seat disconnected, zero while connected, only used by the game loop
+	disconnectedAt [2]time.Time
+	// ticks every second while a player is disconnected
+	graceTicker *time.Ticker
+
+	// nil for untimed games, only used by the game loop
+	clock *clock.Clock
+	// fires when the running side's time runs out
+	flagTimer *time.Timer
+
+	// names of the players in each seat, for exporting the game
+	namesMu sync.Mutex
+	names   [2]string
+
+	// closed once the game loop has finished, after which finalGame no longer changes
+	done      chan struct{}
+	finalGame models.ChessGame
 }
 
-func NewGame(numberOfPlayers int, gameID string, delete func(id string)) *Game {
-	return &Game{
-		GameID:          gameID,
-		Delete:          delete,
-		NumberOfPlayers: numberOfPlayers,
-		Clients:         make([]*Client, 0, numberOfPlayers),
-		Register:        make(chan *Client),
-		Unregister:      make(chan *Client),
-		RecieveMove:     make(chan APIMove),
+// creates a game between the given players, seats given a nil player are open for clients to join
+// the zero time control is an untimed game
+func NewGame(gameID string, onFinish func(id string), players [2]Player, timeControl clock.TimeControl) *Game {
+	game := &Game{
+		GameID:         gameID,
+		OnFinish:       onFinish,
+		StartTime:      time.Now(),
+		TimeControl:    timeControl,
+		Players:        players,
+		Register:       make(chan *Client),
+		Unregister:     make(chan *Client),
+		RecieveMove:    make(chan SeatMove),
+		Snapshot:       make(chan chan models.ChessGame),
+		announcements:  make(chan string),
+		ReconnectGrace: DefaultReconnectGrace,
+		StartTimeout:   DefaultStartTimeout,
+		seats:          players,
+		spectators:     make(map[*Client]bool),
+		names:          playerNames(players),
+		done:           make(chan struct{}),
+	}
+	if !timeControl.Untimed() {
+		game.clock = clock.New(timeControl)
 	}
+	return game
 }
 
-func (game *Game) Start() {
-	defer func() {
-		game.Delete(game.GameID)
-	}()
+// continues a saved game from its position and the time each side had left, instead of starting a new one
+// must be called before the game starts
+func (game *Game) Restore(chessGame models.ChessGame, remaining [2]time.Duration) {
+	game.restored = &chessGame
+	if game.clock != nil {
+		for seat, left := range remaining {
+			game.clock.SetRemaining(seat, left)
+		}
+	}
+}
+
+// seats the client in the first open seat, or else has them watch, returning false if the game is over
+// the game loop seats clients one at a time, so two clients joining at once can never take the same seat
+func (game *Game) Join(client *Client) bool {
+	select {
+	case game.Register <- client:
+	case <-game.done:
+		return false
+	}
+	return <-client.joined
+}
+
+// submits a move for the player in a seat, it is ignored if the game has ended
+func (game *Game) SubmitMove(seat int, move APIMove) {
+	game.submit(SeatMove{Seat: seat, Move: move})
+}
+
+// hands a move to the game loop, it is ignored if the game has ended
+func (game *Game) submit(seatMove SeatMove) {
+	select {
+	case game.RecieveMove <- seatMove:
+	case <-game.done:
+	}
+}
+
+// sends a message to every player and spectator, it is ignored if the game has ended
+func (game *Game) Announce(text string) {
+	select {
+	case game.announcements <- text:
+	case <-game.done:
+	}
+}
+
+// returns a copy of the chess game, for both running and finished games
+func (game *Game) ChessGame() models.ChessGame {
+	reply := make(chan models.ChessGame, 1)
+	select {
+	case game.Snapshot <- reply:
+		return <-reply
+	case <-game.done:
+		return game.finalGame.Copy()
+	}
+}
+
+// returns the PGN tags describing a game between the named players
+func NewPGNTags(startTime time.Time, names [2]string, timeControl clock.TimeControl, result models.Result) models.PGNTags {
+	tags := models.PGNTags{
+		"Event": "go-chess game",
+		"Site":  "go-chess",
+		"Date":  startTime.Format("2006.01.02"),
+		"Round": "-",
+	}
+	for seat, name := range names {
+		if name != "" {
+			tags[[2]string{"White", "Black"}[seat]] = name
+		}
+	}
+	if !timeControl.Untimed() {
+		tags["TimeControl"] = timeControl.PGNTag()
+	}
+	if result.IsTimeout() {
+		tags["Termination"] = "time forfeit"
+	} else if result == models.Aborted {
+		tags["Termination"] = "abandoned"
+	}
+	return tags
+}
+
+// returns the names of the players in each seat, "" for open seats and players without a name
+func (game *Game) Names() [2]string {
+	game.namesMu.Lock()
+	defer game.namesMu.Unlock()
+	return game.names
+}
+
+func playerNames(players [2]Player) [2]string {
+	var names [2]string
+	for seat, player := range players {
+		if player != nil {
+			names[seat] = player.Name()
+		}
+	}
+	return names
+}
+
+// returns the game in PGN export format
+func (game *Game) PGN() (string, error) {
+	chessGame := game.ChessGame()
+	tags := NewPGNTags(game.StartTime, game.Names(), game.TimeControl, chessGame.Winner)
+	return chessGame.PGN(tags)
+}
+
+// returns the seat the client should take: the seat its token is for, even if an older connection still holds it,
+// or else the open seat reserved for them, or else the first unreserved open seat not held for a disconnected player
+// returns -1 if there is none
+func (game *Game) claimSeat(client *Client) int {
+	if client.SeatToken != "" {
+		for seat, token := range game.seatTokens {
+			if token != client.SeatToken {
+				continue
+			}
+			if human, ok := game.seats[seat].(*HumanPlayer); ok {
+				// the old connection has not noticed it was lost yet, closing it stops its reader
+				// and any move it already read is dropped as it no longer holds the seat
+				human.Client.close()
+				game.seats[seat] = nil
+			}
+			if game.seats[seat] == nil {
+				return seat
+			}
+		}
+	}
+	return game.openSeat(client.Username)
+}
+
+// returns the open seat reserved for the username, or else the first unreserved open seat
+// seats of players who disconnected during the game are only given back to them
+func (game *Game) openSeat(username string) int {
+	for seat, player := range game.seats {
+		if player == nil && username != "" && game.Reserved[seat] == username {
+			return seat
+		}
+	}
+	for seat, player := range game.seats {
+		if player == nil && game.Reserved[seat] == "" && game.disconnectedAt[seat].IsZero() {
+			return seat
+		}
+	}
+	return -1
+}
+
+// returns true once every seat has a player
+func (game *Game) seatsFilled() bool {
+	return game.seats[WhiteSeat] != nil && game.seats[BlackSeat] != nil
+}
+
+// returns the state of the game as seen from a seat, or by a spectator, including the clock
+// must be called from the game loop
+func (game *Game) apiState(chessGame models.ChessGame, seat int) APIState {
+	state := convertToAPIState(chessGame, seat)
+	state.Clock = convertToAPIClock(game.clock)
+	state.Spectators = len(game.spectators)
+	return state
+}
+
+// starts the game once every seat is filled, with the time of the side to move running
+func (game *Game) begin(chessGame models.ChessGame) {
+	game.started = true
+	if game.startTimer != nil {
+		game.startTimer.Stop()
+	}
+	game.event(GameStarted, chessGame)
+	if game.clock != nil {
+		game.clock.Start(int(chessGame.CurrentState.Turn))
+		game.resetFlagTimer()
+	}
+	game.updatePlayers(chessGame)
+}
+
+// returns the channel the start timer fires on, nil once the game has begun or if it waits forever
+func (game *Game) startExpired() <-chan time.Time {
+	if game.started || game.startTimer == nil {
+		return nil
+	}
+	return game.startTimer.C
+}
+
+// makes the flag timer fire when the running side's time runs out
+func (game *Game) resetFlagTimer() {
+	if game.flagTimer == nil {
+		game.flagTimer = time.NewTimer(game.clock.TimeToFlag())
+		return
+	}
+	if !game.flagTimer.Stop() {
+		select {
+		case <-game.flagTimer.C:
+		default:
+		}
+	}
+	game.flagTimer.Reset(game.clock.TimeToFlag())
+}
+
+// returns the channel the flag timer fires on, nil while no time is running
+func (game *Game) flagFall() <-chan time.Time {
+	if game.clock == nil || game.clock.Running() < 0 || game.flagTimer == nil {
+		return nil
+	}
+	return game.flagTimer.C
+}
+
+// records a move that has just been played, with the clock already running for the other side
+func (game *Game) recordMove(move models.Move) {
+	if game.Recorder == nil {
+		return
+	}
+	var remaining [2]time.Duration
+	if game.clock != nil {
+		remaining = [2]time.Duration{game.clock.Remaining(WhiteSeat), game.clock.Remaining(BlackSeat)}
+	}
+	if err := game.Recorder.RecordMove(game.GameID, move, remaining); err != nil {
+		log.Println(err)
+	}
+}
+
+// ends the game because the side to move has run out of time
+func (game *Game) timeout(chessGame *models.ChessGame) {
+	game.clock.Stop()
+	chessGame.Timeout()
+	game.updatePlayers(*chessGame)
+	game.broadcastResult(chessGame.Winner)
+}
+
+// tells every player and spectator about the new state of the game
+func (game *Game) updatePlayers(chessGame models.ChessGame) {
+	for seat, player := range game.seats {
+		if player != nil {
+			player.Update(game, chessGame, seat)
+		}
+	}
+	if len(game.spectators) > 0 {
+		message := NewMessage(UpdateStateMessage, "", game.apiState(chessGame, SpectatorSeat))
+		for client := range game.spectators {
+			client.Send(message)
+		}
+	}
+}
+
+// returns a new random token for rejoining a seat
+func newSeatToken() string {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		log.Println(err)
+	}
+	return hex.EncodeToString(token)
+}
+
+// returns the channel the reconnect countdown ticks on, nil while every player is connected
+func (game *Game) graceTick() <-chan time.Time {
+	if game.graceTicker == nil {
+		return nil
+	}
+	return game.graceTicker.C
+}
+
+// holds the seat of a player who disconnected from a game in progress and starts their countdown
+func (game *Game) disconnected(seat int) {
+	game.disconnectedAt[seat] = time.Now()
+	if game.graceTicker == nil {
+		game.graceTicker = time.NewTicker(time.Second)
+	}
+	game.broadcast(NewMessage(MiscMessage, "Opponent Disconnected", CreateEmptyGameState()))
+	game.broadcastCountdown()
+}
+
+// stops the countdown of a seat whose player has reconnected
+func (game *Game) reconnected(seat int) {
+	game.disconnectedAt[seat] = time.Time{}
+	if game.disconnectedAt[WhiteSeat].IsZero() && game.disconnectedAt[BlackSeat].IsZero() && game.graceTicker != nil {
+		game.graceTicker.Stop()
+		game.graceTicker = nil
+	}
+	game.broadcast(NewMessage(MiscMessage, "Opponent Reconnected", CreateEmptyGameState()))
+}
+
+// tells the connected players how long each disconnected player has left to reconnect
+func (game *Game) broadcastCountdown() {
+	for seat := range game.disconnectedAt {
+		if left := game.graceLeft(seat); left > 0 {
+			seconds := (left + time.Second - 1) / time.Second
+			game.broadcast(NewMessage(ReconnectMessage, fmt.Sprint(int(seconds)), CreateEmptyGameState()))
+		}
+	}
+}
+
+// returns how long the player in a seat has left to reconnect, 0 if they are connected
+func (game *Game) graceLeft(seat int) time.Duration {
+	if game.disconnectedAt[seat].IsZero() {
+		return 0
+	}
+	if left := game.ReconnectGrace - time.Since(game.disconnectedAt[seat]); left > 0 {
+		return left
+	}
+	return time.Nanosecond
+}
+
+// returns true if the player in the seat has not reconnected in time
+func (game *Game) abandoned(seat int) bool {
+	at := game.disconnectedAt[seat]
+	return !at.IsZero() && time.Since(at) >= game.ReconnectGrace
+}
+
+// returns the result once a player has not reconnected in time: they lose, or the game is aborted if neither player came back
+// returns models.ContinueGame while every disconnected player still has time left
+func (game *Game) abandonedResult() models.Result {
+	white, black := game.abandoned(WhiteSeat), game.abandoned(BlackSeat)
+	switch {
+	case white && black:
+		return models.Aborted
+	case white:
+		return models.BlackWins
+	case black:
+		return models.WhiteWins
+	default:
+		return models.ContinueGame
+	}
+}
+
+// tells the listener, if there is one, about the game's progress
+func (game *Game) event(event GameEvent, chessGame models.ChessGame) {
+	if game.OnEvent != nil {
+		game.OnEvent(event, chessGame)
+	}
+}
+
+// sends a message to every player and spectator
+func (game *Game) broadcast(message Message) {
+	for _, player := range game.seats {
+		if player != nil {
+			player.Notify(message)
+		}
+	}
+	for client := range game.spectators {
+		client.Send(message)
+	}
+}
+
+// tells every player and spectator how many spectators there are, after one joins or leaves
+func (game *Game) broadcastSpectators() {
+	game.broadcast(NewMessage(SpectatorsMessage, fmt.Sprint(len(game.spectators)), CreateEmptyGameState()))
+}
 
+// sends the end of game message, e.g. the checkmate winner or the reason for a draw, to every player and spectator
+func (game *Game) broadcastResult(result models.Result) {
+	game.broadcast(NewMessage(GameInfoMessage, result.Description(), CreateEmptyGameState()))
+}
+
+// resolves a submitted move to one of the possible moves, from its type and squares or else its UCI or SAN
+func parseSubmittedMove(chessGame models.ChessGame, move APIMove) (models.Move, bool) {
+	if move.UCI != "" && move.MoveType == "" {
+		uciMove, err := chessGame.CurrentState.ParseUCI(move.UCI)
+		return uciMove, err == nil
+	}
+	if move.SAN != "" && move.MoveType == "" {
+		sanMove, err := chessGame.CurrentState.ParseSAN(move.SAN)
+		return sanMove, err == nil
+	}
+	tryMove := convertToMove(move)
+	return tryMove, chessGame.IsPossibleMove(tryMove)
+}
+
+func (game *Game) Start() {
 	// create new game
 	chessGame := models.NewChessGame()
-	gameOver := false
+	if game.restored != nil {
+		chessGame = *game.restored
+	}
+	gameOver := chessGame.Winner != models.ContinueGame
+
+	defer func() {
+		if game.Recorder != nil {
+			if err := game.Recorder.RecordResult(game.GameID, chessGame.Winner); err != nil {
+				log.Println(err)
+			}
+		}
+		if game.flagTimer != nil {
+			game.flagTimer.Stop()
+		}
+		if game.startTimer != nil {
+			game.startTimer.Stop()
+		}
+		if game.graceTicker != nil {
+			game.graceTicker.Stop()
+		}
+		game.event(GameEnded, chessGame)
+		game.finalGame = chessGame
+		close(game.done)
+		for _, player := range game.seats {
+			if player != nil {
+				player.Close()
+			}
+		}
+		if game.OnFinish != nil {
+			game.OnFinish(game.GameID)
+		}
+	}()
 
-	var message Message
+	// games between computer players start straight away, others are aborted if nobody comes to play them
+	if game.seatsFilled() {
+		game.begin(chessGame)
+	} else if !gameOver && len(chessGame.MoveHistory) > 0 {
+		// a restored game was already under way, so its players are treated as having disconnected
+		game.begin(chessGame)
+		for seat, player := range game.seats {
+			if player == nil {
+				game.disconnected(seat)
+			}
+		}
+	} else if !gameOver && game.StartTimeout > 0 {
+		game.startTimer = time.NewTimer(game.StartTimeout)
+	}
 
 	for !gameOver {
 		select {
+		case reply := <-game.Snapshot:
+			reply <- chessGame.Copy()
+		case text := <-game.announcements:
+			game.broadcast(NewMessage(MiscMessage, text, CreateEmptyGameState()))
 		case client := <-game.Register:
 			log.Println("Doing register work...")
-			game.Clients = append(game.Clients, client)
-			// if we have enough players start the game
-			if len(game.Clients) == game.NumberOfPlayers {
-				for i, c := range game.Clients {
-					// send message
-					message = NewMessage(UpdateStateMessage, "", convertToAPIState(chessGame, i))
-					log.Println(message)
-					// TODO: Error checking
-					c.Conn.WriteJSON(message)
+			seat := game.claimSeat(client)
+			if seat < 0 {
+				// no seat for them, so they watch
+				client.Seat = SpectatorSeat
+				game.spectators[client] = true
+				client.joined <- true
+				if game.started {
+					client.Send(NewMessage(UpdateStateMessage, "", game.apiState(chessGame, SpectatorSeat)))
 				}
+				game.broadcastSpectators()
+				break
+			}
+			if !game.disconnectedAt[seat].IsZero() {
+				game.reconnected(seat)
+			}
+			client.Seat = seat
+			player := &HumanPlayer{Client: client}
+			game.seats[seat] = player
+			game.namesMu.Lock()
+			game.names[seat] = client.Username
+			game.namesMu.Unlock()
+			client.joined <- true
+
+			if game.seatTokens[seat] == "" {
+				game.seatTokens[seat] = newSeatToken()
+			}
+			player.Notify(NewMessage(SeatTokenMessage, game.seatTokens[seat], CreateEmptyGameState()))
+
+			if game.started {
+				// rejoining, so restore the player's view of the game
+				player.Update(game, chessGame, seat)
+			} else if game.seatsFilled() {
+				// if we have enough players start the game
+				game.begin(chessGame)
 			}
 		case client := <-game.Unregister:
-			for i, c := range game.Clients {
-				if c == client {
-					game.Clients[i] = nil
-				} else {
-					// set winner to whichever client did not disconnect
-					winner := ""
-					if i == 0 {
-						winner = "White"
-					} else {
-						winner = "Black"
-					}
-
-					// send message
-					message = NewMessage(MiscMessage, "Opponent Disconnected", CreateEmptyGameState())
-					// TODO: Error checking
-					c.Conn.WriteJSON(message)
-
-					message = NewMessage(GameInfoMessage, fmt.Sprintf("%s wins!", winner), CreateEmptyGameState())
-					// TODO: Error checking
-					c.Conn.WriteJSON(message)
-				}
+			if client.Seat == SpectatorSeat {
+				delete(game.spectators, client)
+				game.broadcastSpectators()
+				break
+			}
+			// connections replaced by a newer one for the same seat have already left it
+			if human, ok := game.seats[client.Seat].(*HumanPlayer); !ok || human.Client != client {
+				break
+			}
+			game.seats[client.Seat] = nil
+			// before the game begins the seat is simply free again
+			if game.started {
+				game.disconnected(client.Seat)
+			}
+		case <-game.graceTick():
+			result := game.abandonedResult()
+			if result == models.ContinueGame {
+				game.broadcastCountdown()
+				break
+			}
+			chessGame.Winner = result
+			if game.clock != nil {
+				game.clock.Stop()
 			}
+			if result == models.Aborted {
+				game.broadcast(NewMessage(MiscMessage, "Neither player reconnected", CreateEmptyGameState()))
+			} else {
+				game.broadcast(NewMessage(MiscMessage, "Opponent did not reconnect", CreateEmptyGameState()))
+			}
+			game.broadcastResult(result)
 			gameOver = true
-		case move := <-game.RecieveMove:
-
-			// check that move was in possible moves
-			tryMove := convertToMove(move)
-			isAllowedMove := false
-			for _, possibleMove := range chessGame.PossibleMoves {
-				if tryMove == possibleMove {
-					isAllowedMove = true
-					break
-				}
+		case <-game.startExpired():
+			log.Printf("Game %v was not started in time.", game.GameID)
+			chessGame.Winner = models.Aborted
+			game.broadcastResult(chessGame.Winner)
+			gameOver = true
+		case <-game.flagFall():
+			// the timer can fire a little early, so check the clock itself
+			if game.clock.TimeToFlag() > 0 {
+				game.resetFlagTimer()
+				break
 			}
-			if !isAllowedMove {
-				message = NewMessage(MiscMessage, "Invalid Move.", CreateEmptyGameState())
-				// TODO: Error checking
-				for _, client := range game.Clients {
-					client.Conn.WriteJSON(message)
-				}
+			log.Println("Flag fell.")
+			game.timeout(&chessGame)
+			gameOver = true
+		case seatMove := <-game.RecieveMove:
+			// spectators cannot move
+			if seatMove.Seat == SpectatorSeat {
 				break
 			}
-
-			// execute move
-			chessGame.ExecuteMoveOnGame(tryMove)
-
-			// send back updated state
-			for i, client := range game.Clients {
-				log.Println("Move recieved.")
-				// send updated state
-				message = NewMessage(UpdateStateMessage, "", convertToAPIState(chessGame, i))
-				client.Conn.WriteJSON(message)
+			player := game.seats[seatMove.Seat]
+			if player == nil {
+				// sent by a connection that has since left its seat
+				break
 			}
-
-			// check if game is ended
-
-			// TODO: Refactor logic
-			if chessGame.Winner == models.Stalemate {
-				for _, client := range game.Clients {
-					// send message
-					message = NewMessage(GameInfoMessage, "Stalemate", CreateEmptyGameState())
-					client.Conn.WriteJSON(message)
-				}
-				gameOver = true
+			if human, ok := player.(*HumanPlayer); seatMove.Client != nil && (!ok || human.Client != seatMove.Client) {
+				// sent by a connection that has since been replaced by a newer one for the same seat
 				break
-			} else if chessGame.Winner == models.WhiteWins {
-				for _, client := range game.Clients {
-					// send message
-					message = NewMessage(GameInfoMessage, "White wins!", CreateEmptyGameState())
-					client.Conn.WriteJSON(message)
-				}
-				gameOver = true
+			}
+			if !game.started {
+				player.Notify(NewMessage(MiscMessage, "The game has not started.", CreateEmptyGameState()))
 				break
-			} else if chessGame.Winner == models.BlackWins {
-				for _, client := range game.Clients {
-					// send message
-					message = NewMessage(GameInfoMessage, "Black wins!", CreateEmptyGameState())
-					client.Conn.WriteJSON(message)
-				}
-				gameOver = true
+			}
+			if seatMove.Seat != int(chessGame.CurrentState.Turn) {
+				player.Notify(NewMessage(MiscMessage, "It is not your turn.", CreateEmptyGameState()))
 				break
 			}
 
-			// game continues... (pass move to player or execute computer move)
-			if game.NumberOfPlayers == 1 {
-				// execute random move
-				chessGame.ExecuteMoveOnGame(chessGame.PossibleMoves[rand.Intn(len(chessGame.PossibleMoves))])
+			// check that move was in possible moves
+			tryMove, isAllowedMove := parseSubmittedMove(chessGame, seatMove.Move)
+			if !isAllowedMove {
+				player.Notify(NewMessage(MiscMessage, "Invalid Move.", CreateEmptyGameState()))
+				break
+			}
 
-				// send back updated state
-				for i, client := range game.Clients {
-					// send message
-					message = NewMessage(UpdateStateMessage, "", convertToAPIState(chessGame, i))
-					client.Conn.WriteJSON(message)
-				}
+			// a move made after the flag fell loses on time
+			if game.clock != nil && !game.clock.Press() {
+				game.timeout(&chessGame)
+				gameOver = true
+				break
+			}
 
-				// check if game is ended
-
-				// TODO: Refactor logic
-				if chessGame.Winner == models.Stalemate {
-					for _, client := range game.Clients {
-						// send message
-						message = NewMessage(GameInfoMessage, "Stalemate", CreateEmptyGameState())
-						client.Conn.WriteJSON(message)
-					}
-					gameOver = true
-					break
-				} else if chessGame.Winner == models.WhiteWins {
-					for _, client := range game.Clients {
-						// send message
-						message = NewMessage(GameInfoMessage, "White wins!", CreateEmptyGameState())
-						client.Conn.WriteJSON(message)
-					}
-					gameOver = true
-					break
-				} else if chessGame.Winner == models.BlackWins {
-					for _, client := range game.Clients {
-						// send message
-						message = NewMessage(GameInfoMessage, "Black wins!", CreateEmptyGameState())
-						client.Conn.WriteJSON(message)
-					}
-					gameOver = true
-					break
+			// execute move and send back updated state
+			log.Println("Move recieved.")
+			chessGame.ExecuteMoveOnGame(tryMove)
+			game.recordMove(tryMove)
+			game.event(GameMoved, chessGame)
+			if game.clock != nil {
+				if chessGame.Winner != models.ContinueGame {
+					game.clock.Stop()
+				} else {
+					game.resetFlagTimer()
 				}
 			}
+			game.updatePlayers(chessGame)
+
+			// check if game is ended
+			if chessGame.Winner != models.ContinueGame {
+				game.broadcastResult(chessGame.Winner)
+				gameOver = true
+			}
 		}
 	}
 }
@@ -0,0 +1,199 @@
+package sockets
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/clock"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	wsclient "github.com/fasthttp/websocket"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// starts an untimed game with two open seats, served like the real server at ws://addr/game?user=...&seat=...
+func startTestGame(t *testing.T, reconnectGrace time.Duration) (*Game, string) {
+	t.Helper()
+	game := NewGame("test", nil, [2]Player{}, clock.TimeControl{})
+	game.ReconnectGrace = reconnectGrace
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/game", websocket.New(func(conn *websocket.Conn) {
+		client := NewClient(conn, game)
+		client.Username = conn.Query("user")
+		client.SeatToken = conn.Query("seat")
+		if game.Join(client) {
+			client.Read()
+		}
+	}))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() { app.Shutdown() })
+
+	go game.Start()
+	return game, listener.Addr().String()
+}
+
+// connects to the test game as a user, with the seat token of an earlier connection if there is one
+func dialTestGame(t *testing.T, addr string, username string, seatToken string) *wsclient.Conn {
+	t.Helper()
+	conn, _, err := wsclient.DefaultDialer.Dial(fmt.Sprintf("ws://%v/game?user=%v&seat=%v", addr, username, seatToken), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// reads messages until one of the wanted type arrives, failing the test if none does
+func nextMessage(t *testing.T, conn *wsclient.Conn, messageType int) Message {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var message Message
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("waiting for message type %d: %v", messageType, err)
+		}
+		if message.MessageType == messageType {
+			return message
+		}
+	}
+}
+
+// seats alice as white and bobby as black, returning their connections and alice's seat token once the game has begun
+func seatPlayers(t *testing.T, addr string) (*wsclient.Conn, *wsclient.Conn, string) {
+	t.Helper()
+	white := dialTestGame(t, addr, "alice", "")
+	token := nextMessage(t, white, SeatTokenMessage).MessageContent
+	black := dialTestGame(t, addr, "bobby", "")
+	nextMessage(t, black, SeatTokenMessage)
+	nextMessage(t, white, UpdateStateMessage)
+	nextMessage(t, black, UpdateStateMessage)
+	return white, black, token
+}
+
+// waits for the game loop to finish and returns the result
+func waitForResult(t *testing.T, game *Game) models.Result {
+	t.Helper()
+	select {
+	case <-game.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("game did not end")
+	}
+	return game.ChessGame().Winner
+}
+
+func TestReconnectWithinGrace(t *testing.T) {
+	game, addr := startTestGame(t, time.Minute)
+	white, black, token := seatPlayers(t, addr)
+
+	white.Close()
+	nextMessage(t, black, ReconnectMessage)
+	white = dialTestGame(t, addr, "alice", token)
+	if got := nextMessage(t, white, SeatTokenMessage).MessageContent; got != token {
+		t.Errorf("rejoined with seat token %q, want %q", got, token)
+	}
+	nextMessage(t, white, UpdateStateMessage)
+
+	// the game carries on where it was
+	if err := white.WriteJSON(APIMove{UCI: "e2e4"}); err != nil {
+		t.Fatal(err)
+	}
+	if state := nextMessage(t, black, UpdateStateMessage).GameState; len(state.PreviousMoves) != 1 {
+		t.Errorf("black sees %d moves, want 1", len(state.PreviousMoves))
+	}
+	if winner := game.ChessGame().Winner; winner != models.ContinueGame {
+		t.Errorf("winner = %q, want the game to continue", winner)
+	}
+}
+
+func TestGraceExpiryForfeits(t *testing.T) {
+	game, addr := startTestGame(t, 100*time.Millisecond)
+	white, black, _ := seatPlayers(t, addr)
+
+	black.Close()
+	if got := nextMessage(t, white, GameInfoMessage).MessageContent; got != models.WhiteWins.Description() {
+		t.Errorf("white was told %q, want %q", got, models.WhiteWins.Description())
+	}
+	if winner := waitForResult(t, game); winner != models.WhiteWins {
+		t.Errorf("winner = %q, want %q", winner, models.WhiteWins)
+	}
+}
+
+func TestNeitherPlayerReconnectingAborts(t *testing.T) {
+	game, addr := startTestGame(t, 100*time.Millisecond)
+	white, black, _ := seatPlayers(t, addr)
+
+	white.Close()
+	black.Close()
+	if winner := waitForResult(t, game); winner != models.Aborted {
+		t.Errorf("winner = %q, want %q", winner, models.Aborted)
+	}
+}
+
+func TestSeatTokenTakeover(t *testing.T) {
+	game, addr := startTestGame(t, time.Minute)
+	white, black, token := seatPlayers(t, addr)
+
+	// alice connects again while her first connection still holds the seat
+	takeover := dialTestGame(t, addr, "alice", token)
+	if got := nextMessage(t, takeover, SeatTokenMessage).MessageContent; got != token {
+		t.Errorf("took over with seat token %q, want %q", got, token)
+	}
+	nextMessage(t, takeover, UpdateStateMessage)
+
+	// the replaced connection is closed
+	white.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var message Message
+		err := white.ReadJSON(&message)
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			t.Fatal("replaced connection was not closed")
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// moves from a connection that no longer holds the seat are dropped
+	game.submit(SeatMove{Seat: WhiteSeat, Client: &Client{}, Move: APIMove{UCI: "e2e4"}})
+	if moves := len(game.ChessGame().MoveHistory); moves != 0 {
+		t.Fatalf("%d moves played by a replaced connection", moves)
+	}
+
+	if err := takeover.WriteJSON(APIMove{UCI: "e2e4"}); err != nil {
+		t.Fatal(err)
+	}
+	if state := nextMessage(t, black, UpdateStateMessage).GameState; len(state.PreviousMoves) != 1 {
+		t.Errorf("black sees %d moves, want 1", len(state.PreviousMoves))
+	}
+}
+
+func TestWrongSeatTokenIsRejected(t *testing.T) {
+	_, addr := startTestGame(t, time.Minute)
+	white, black, token := seatPlayers(t, addr)
+
+	white.Close()
+	nextMessage(t, black, ReconnectMessage)
+
+	// without the right token the held seat is not given away, so the connection only watches
+	intruder := dialTestGame(t, addr, "mallory", "wrong")
+	intruder.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message Message
+	if err := intruder.ReadJSON(&message); err != nil {
+		t.Fatal(err)
+	}
+	if message.MessageType != UpdateStateMessage || message.GameState.Spectators != 1 {
+		t.Errorf("connection with a wrong seat token first got %+v, want the spectator's game state", message)
+	}
+
+	white = dialTestGame(t, addr, "alice", token)
+	if got := nextMessage(t, white, SeatTokenMessage).MessageContent; got != token {
+		t.Errorf("rejoined with seat token %q, want %q", got, token)
+	}
+}
@@ -4,6 +4,12 @@ const (
 	UpdateStateMessage = iota
 	GameInfoMessage
 	MiscMessage
+	// the token for rejoining the player's seat, sent when they join
+	SeatTokenMessage
+	// an opponent has disconnected, the content is the number of seconds left for them to reconnect
+	ReconnectMessage
+	// someone started or stopped watching, the content is the number of spectators
+	SpectatorsMessage
 )
 
 type Message struct {
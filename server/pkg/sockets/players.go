@@ -0,0 +1,118 @@
+package sockets
+
+import (
+	"log"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/engine"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	"github.com/BrianJHenry/go-chess/server/pkg/opponent"
+)
+
+// seats, the same as the colors
+const (
+	WhiteSeat = models.White
+	BlackSeat = models.Black
+	// clients watching the game rather than playing
+	SpectatorSeat = -1
+)
+
+// a move submitted by the player in one seat
+type SeatMove struct {
+	Seat int
+	// the connection the move was read from, nil for players that are not clients
+	// the move is dropped unless the connection still holds the seat
+	Client *Client
+	Move   APIMove
+}
+
+// one side of a game
+// the game loop calls every method from its own goroutine, one at a time
+type Player interface {
+	// name used in exported games, "" if unknown
+	Name() string
+	// tells the player the game has changed, including when it starts
+	// when it is the player's turn it answers with game.SubmitMove for its seat, Update itself must not block
+	Update(game *Game, chessGame models.ChessGame, seat int)
+	// sends a message to the player, such as the result of the game
+	Notify(message Message)
+	// releases the player's resources once the game has ended
+	Close()
+}
+
+// a person playing through a websocket client
+type HumanPlayer struct {
+	Client *Client
+}
+
+func (player *HumanPlayer) Name() string {
+	return player.Client.Username
+}
+
+func (player *HumanPlayer) Update(game *Game, chessGame models.ChessGame, seat int) {
+	player.Notify(NewMessage(UpdateStateMessage, "", game.apiState(chessGame, seat)))
+}
+
+func (player *HumanPlayer) Notify(message Message) {
+	player.Client.Send(message)
+}
+
+func (player *HumanPlayer) Close() {}
+
+// a computer opponent, thinking in its own goroutine so the game keeps serving other requests
+type ComputerPlayer struct {
+	opponent opponent.Opponent
+	// the opponent's name, kept so it can be read while the opponent is thinking
+	name string
+	// level the built-in engine takes over at if the opponent fails
+	fallback engine.Level
+
+	// number of moves in the game when the opponent last started thinking, so a position is only searched once
+	thinkingAt int
+	// closed when the opponent has finished thinking
+	thinking chan struct{}
+}
+
+// creates a computer player, which switches to the built-in engine at the fallback level if the opponent fails
+func NewComputerPlayer(opponent opponent.Opponent, fallback engine.Level) *ComputerPlayer {
+	return &ComputerPlayer{opponent: opponent, name: opponent.Name(), fallback: fallback, thinkingAt: -1}
+}
+
+func (player *ComputerPlayer) Name() string {
+	return player.name
+}
+
+func (player *ComputerPlayer) Update(game *Game, chessGame models.ChessGame, seat int) {
+	if int(chessGame.CurrentState.Turn) != seat || chessGame.Winner != models.ContinueGame || player.thinkingAt == len(chessGame.MoveHistory) {
+		return
+	}
+	player.thinkingAt = len(chessGame.MoveHistory)
+	thinking := make(chan struct{})
+	player.thinking = thinking
+
+	go func() {
+		defer close(thinking)
+		move, err := player.opponent.Move(&chessGame)
+		if err != nil {
+			// keep the game going with the built-in engine rather than abandoning it
+			log.Printf("Computer opponent %v failed, switching to the built-in engine: %v", player.name, err)
+			player.opponent.Close()
+			player.opponent = opponent.NewEngine(player.fallback)
+			game.Announce("The computer opponent failed, the built-in engine is playing instead.")
+			move, _ = player.opponent.Move(&chessGame)
+		}
+		log.Printf("Computer plays %v", move.UCI())
+		game.SubmitMove(seat, convertToAPIMove(move))
+	}()
+}
+
+func (player *ComputerPlayer) Notify(message Message) {}
+
+// waits for the opponent to finish thinking before closing it
+func (player *ComputerPlayer) Close() {
+	if player.thinking != nil {
+		<-player.thinking
+	}
+	if err := player.opponent.Close(); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,89 @@
+package sockets
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+// the games being played by ID, safe for concurrent use
+// a game is added under an ID it reserved, or the ID of the stored game it continues, and removed when its loop finishes
+type Registry struct {
+	mu    sync.RWMutex
+	games map[string]*Game
+	// IDs handed out by ReserveID whose game has not been added or released yet
+	reserved map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		games:    make(map[string]*Game),
+		reserved: make(map[string]bool),
+	}
+}
+
+// returns a new ID, used by no registered or reserved game nor any ID taken reports as used, e.g. by a stored game
+// the ID stays reserved until a game is added with it or it is released
+func (registry *Registry) ReserveID(taken func(gameID string) bool) string {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	for {
+		gameID := fmt.Sprint(rand.Intn(10000000))
+		if _, ok := registry.games[gameID]; ok || registry.reserved[gameID] || taken(gameID) {
+			continue
+		}
+		registry.reserved[gameID] = true
+		return gameID
+	}
+}
+
+// frees an ID reserved for a game that could not be created
+func (registry *Registry) Release(gameID string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.reserved, gameID)
+}
+
+// adds a game, failing if another game already has its ID, such as when the same stored game is restored twice
+func (registry *Registry) Add(game *Game) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, ok := registry.games[game.GameID]; ok {
+		return fmt.Errorf("game %v is already running", game.GameID)
+	}
+	delete(registry.reserved, game.GameID)
+	registry.games[game.GameID] = game
+	return nil
+}
+
+// runs the loop of an added game, removing the game once it has ended
+func (registry *Registry) Run(game *Game) {
+	go func() {
+		game.Start()
+		registry.remove(game)
+	}()
+}
+
+// returns the running game with the ID
+func (registry *Registry) Get(gameID string) (*Game, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	game, ok := registry.games[gameID]
+	return game, ok
+}
+
+// returns the number of running games
+func (registry *Registry) Len() int {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return len(registry.games)
+}
+
+func (registry *Registry) remove(game *Game) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	// only if it has not been replaced by a game restored with the same ID
+	if registry.games[game.GameID] == game {
+		delete(registry.games, game.GameID)
+	}
+}
@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/clock"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	gamesBucket = []byte("games")
+	// keys inside each game's bucket
+	metadataKey = []byte("metadata")
+	movesBucket = []byte("moves")
+)
+
+// one seat of a stored game
+type PlayerRecord struct {
+	Name string `json:"name"`
+	// computer players are created again from their level and bot when the game is restored
+	Computer bool   `json:"computer,omitempty"`
+	Level    int    `json:"level,omitempty"`
+	Bot      string `json:"bot,omitempty"`
+}
+
+// one move of a stored game
+type MoveRecord struct {
+	UCI string `json:"uci"`
+	// time left for each side once the move was made, zero in untimed games
+	Remaining [2]time.Duration `json:"remaining"`
+	Played    time.Time        `json:"played"`
+}
+
+// a game as it is kept in storage
+type GameRecord struct {
+	ID          string            `json:"id"`
+	Created     time.Time         `json:"created"`
+	TimeControl clock.TimeControl `json:"timeControl"`
+	Rated       bool              `json:"rated"`
+	Players     [2]PlayerRecord   `json:"players"`
+	// models.ContinueGame until the game has ended
+	Winner models.Result `json:"winner"`
+	Ended  time.Time     `json:"ended"`
+	// filled in from the moves stored as the game was played
+	Moves []MoveRecord `json:"-"`
+}
+
+// returns true once the game has a result
+func (record GameRecord) Finished() bool {
+	return record.Winner != models.ContinueGame && record.Winner != ""
+}
+
+// replays the stored moves from the starting position, with the stored result
+func (record GameRecord) ChessGame() (models.ChessGame, error) {
+	chessGame := models.NewChessGame()
+	for i, moveRecord := range record.Moves {
+		move, err := chessGame.CurrentState.ParseUCI(moveRecord.UCI)
+		if err != nil {
+			return chessGame, fmt.Errorf("game %v move %d: %v", record.ID, i+1, err)
+		}
+		chessGame.ExecuteMoveOnGame(move)
+	}
+	if record.Finished() {
+		chessGame.Winner = record.Winner
+	}
+	return chessGame, nil
+}
+
+// returns the time each side had left after the last stored move, or the initial time if no move was stored
+func (record GameRecord) Remaining() [2]time.Duration {
+	if len(record.Moves) == 0 {
+		return [2]time.Duration{record.TimeControl.Initial, record.TimeControl.Initial}
+	}
+	return record.Moves[len(record.Moves)-1].Remaining
+}
+
+// games and their moves kept in a bbolt database, safe for concurrent use
+// each game has its own bucket holding its metadata and a bucket of moves in the order they were played
+type Store struct {
+	db *bolt.DB
+
+	// returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// opens the game store in an existing database, creating its bucket if needed
+func NewStore(db *bolt.DB) (*Store, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(gamesBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db, now: time.Now}, nil
+}
+
+// stores a new game, failing if a game with its ID already exists
+func (store *Store) CreateGame(record GameRecord) error {
+	if record.Winner == "" {
+		record.Winner = models.ContinueGame
+	}
+	return store.db.Update(func(tx *bolt.Tx) error {
+		games := tx.Bucket(gamesBucket)
+		if games.Bucket([]byte(record.ID)) != nil {
+			return fmt.Errorf("game %v already exists", record.ID)
+		}
+		game, err := games.CreateBucket([]byte(record.ID))
+		if err != nil {
+			return err
+		}
+		if _, err := game.CreateBucket(movesBucket); err != nil {
+			return err
+		}
+		return putMetadata(game, record)
+	})
+}
+
+// returns true if a game with the ID has been stored
+func (store *Store) Exists(gameID string) bool {
+	exists := false
+	store.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(gamesBucket).Bucket([]byte(gameID)) != nil
+		return nil
+	})
+	return exists
+}
+
+// appends a move to a stored game
+func (store *Store) RecordMove(gameID string, move models.Move, remaining [2]time.Duration) error {
+	data, err := json.Marshal(MoveRecord{UCI: move.UCI(), Remaining: remaining, Played: store.now()})
+	if err != nil {
+		return err
+	}
+	return store.db.Update(func(tx *bolt.Tx) error {
+		game, err := gameBucket(tx, gameID)
+		if err != nil {
+			return err
+		}
+		moves := game.Bucket(movesBucket)
+		sequence, err := moves.NextSequence()
+		if err != nil {
+			return err
+		}
+		return moves.Put(sequenceKey(sequence), data)
+	})
+}
+
+// stores the result of a game
+func (store *Store) RecordResult(gameID string, result models.Result) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		game, err := gameBucket(tx, gameID)
+		if err != nil {
+			return err
+		}
+		record, err := getMetadata(game)
+		if err != nil {
+			return err
+		}
+		record.Winner = result
+		record.Ended = store.now()
+		return putMetadata(game, record)
+	})
+}
+
+// returns a stored game with its moves
+func (store *Store) Game(gameID string) (GameRecord, bool, error) {
+	var record GameRecord
+	found := false
+	err := store.db.View(func(tx *bolt.Tx) error {
+		game := tx.Bucket(gamesBucket).Bucket([]byte(gameID))
+		if game == nil {
+			return nil
+		}
+		found = true
+		var err error
+		record, err = getGame(game)
+		return err
+	})
+	return record, found, err
+}
+
+// returns every stored game that has not ended, with its moves
+func (store *Store) UnfinishedGames() ([]GameRecord, error) {
+	var records []GameRecord
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(gamesBucket).ForEachBucket(func(id []byte) error {
+			game := tx.Bucket(gamesBucket).Bucket(id)
+			metadata, err := getMetadata(game)
+			if err != nil {
+				return err
+			}
+			if metadata.Finished() {
+				return nil
+			}
+			record, err := getGame(game)
+			if err != nil {
+				return err
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	return records, err
+}
+
+func gameBucket(tx *bolt.Tx, gameID string) (*bolt.Bucket, error) {
+	game := tx.Bucket(gamesBucket).Bucket([]byte(gameID))
+	if game == nil {
+		return nil, fmt.Errorf("game %v does not exist", gameID)
+	}
+	return game, nil
+}
+
+func getGame(game *bolt.Bucket) (GameRecord, error) {
+	record, err := getMetadata(game)
+	if err != nil {
+		return record, err
+	}
+	record.Moves = make([]MoveRecord, 0)
+	err = game.Bucket(movesBucket).ForEach(func(_, data []byte) error {
+		var move MoveRecord
+		if err := json.Unmarshal(data, &move); err != nil {
+			return err
+		}
+		record.Moves = append(record.Moves, move)
+		return nil
+	})
+	return record, err
+}
+
+func getMetadata(game *bolt.Bucket) (GameRecord, error) {
+	var record GameRecord
+	err := json.Unmarshal(game.Get(metadataKey), &record)
+	return record, err
+}
+
+func putMetadata(game *bolt.Bucket, record GameRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return game.Put(metadataKey, data)
+}
+
+// big endian so the moves sort in the order they were played
+func sequenceKey(sequence uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, sequence)
+	return key
+}
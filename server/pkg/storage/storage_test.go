@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/clock"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestDB(t *testing.T, path string) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// plays UCI moves, recording each one
+func recordMoves(t *testing.T, store *Store, gameID string, chessGame *models.ChessGame, ucis ...string) {
+	t.Helper()
+	for i, uci := range ucis {
+		move, err := chessGame.CurrentState.ParseUCI(uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chessGame.ExecuteMoveOnGame(move)
+		remaining := [2]time.Duration{time.Minute - time.Duration(i)*time.Second, time.Minute}
+		if err := store.RecordMove(gameID, move, remaining); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGamesSurviveReopening(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.db")
+	db := openTestDB(t, path)
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeControl := clock.TimeControl{Initial: time.Minute, Increment: time.Second, Mode: clock.Fischer}
+	for _, id := range []string{"running", "finished"} {
+		err := store.CreateGame(GameRecord{
+			ID:          id,
+			Created:     time.Now(),
+			TimeControl: timeControl,
+			Rated:       true,
+			Players:     [2]PlayerRecord{{Name: "alice"}, {Name: "Computer", Computer: true, Level: 3}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.CreateGame(GameRecord{ID: "running"}); err == nil {
+		t.Error("creating a game with a taken ID succeeded")
+	}
+
+	running := models.NewChessGame()
+	recordMoves(t, store, "running", &running, "e2e4", "e7e5", "g1f3")
+	finished := models.NewChessGame()
+	recordMoves(t, store, "finished", &finished, "f2f3", "e7e5", "g2g4", "d8h4")
+	if err := store.RecordResult("finished", finished.Winner); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordMove("missing", models.Move{}, [2]time.Duration{}); err == nil {
+		t.Error("recording a move for a missing game succeeded")
+	}
+	db.Close()
+
+	// everything is still there after reopening the database
+	db = openTestDB(t, path)
+	defer db.Close()
+	store, err = NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !store.Exists("running") || store.Exists("missing") {
+		t.Error("Exists does not match the stored games")
+	}
+
+	unfinished, err := store.UnfinishedGames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unfinished) != 1 || unfinished[0].ID != "running" {
+		t.Fatalf("unfinished games = %+v, want only \"running\"", unfinished)
+	}
+	record := unfinished[0]
+	if record.TimeControl != timeControl || !record.Rated || record.Players[0].Name != "alice" || !record.Players[1].Computer || record.Players[1].Level != 3 {
+		t.Errorf("metadata = %+v", record)
+	}
+	restored, err := record.ChessGame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.CurrentState.FEN() != running.CurrentState.FEN() || restored.Winner != models.ContinueGame {
+		t.Errorf("restored game is at %q with winner %q, want %q", restored.CurrentState.FEN(), restored.Winner, running.CurrentState.FEN())
+	}
+	if want := [2]time.Duration{58 * time.Second, time.Minute}; record.Remaining() != want {
+		t.Errorf("remaining = %v, want %v", record.Remaining(), want)
+	}
+
+	record, found, err := store.Game("finished")
+	if err != nil || !found {
+		t.Fatalf("Game(\"finished\") = %v, %v", found, err)
+	}
+	if record.Winner != models.BlackWins || record.Ended.IsZero() || len(record.Moves) != 4 || record.Moves[3].UCI != "d8h4" {
+		t.Errorf("finished game = %+v", record)
+	}
+	if _, found, _ := store.Game("missing"); found {
+		t.Error("found a game that was never stored")
+	}
+}
@@ -0,0 +1,53 @@
+package transposition
+
+import (
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+// layout of a packed entry, from the least significant bit:
+// move origin (6), move destination (6), move type (3), score (24, offset), depth (8, offset), bound (2), generation (6)
+const (
+	fromShift       = 0
+	toShift         = 6
+	typeShift       = 12
+	scoreShift      = 15
+	depthShift      = 39
+	boundShift      = 47
+	generationShift = 49
+
+	scoreOffset    = 1 << 23
+	depthOffset    = 1 << 7
+	generationMask = 1<<6 - 1
+)
+
+// packs an entry into one word, bound is never NoBound for a stored entry so the word is never 0
+func pack(entry Entry, generation uint8) uint64 {
+	move := entry.Move
+	data := uint64(move.OldSquare.Row*8+move.OldSquare.Col) << fromShift
+	data |= uint64(move.NewSquare.Row*8+move.NewSquare.Col) << toShift
+	data |= uint64(move.Type) << typeShift
+	data |= uint64(entry.Score+scoreOffset) << scoreShift
+	data |= uint64(entry.Depth+depthOffset) << depthShift
+	data |= uint64(entry.Bound) << boundShift
+	data |= uint64(generation&generationMask) << generationShift
+	return data
+}
+
+func unpack(data uint64) Entry {
+	from := int(data>>fromShift) & 63
+	to := int(data>>toShift) & 63
+	return Entry{
+		Move: models.Move{
+			Type:      models.MoveType(data>>typeShift) & 7,
+			OldSquare: models.Location{Row: from / 8, Col: from % 8},
+			NewSquare: models.Location{Row: to / 8, Col: to % 8},
+		},
+		Score: int(data>>scoreShift&(1<<24-1)) - scoreOffset,
+		Depth: int(data>>depthShift&(1<<8-1)) - depthOffset,
+		Bound: Bound(data >> boundShift & 3),
+	}
+}
+
+func entryGeneration(data uint64) uint8 {
+	return uint8(data>>generationShift) & generationMask
+}
@@ -0,0 +1,207 @@
+package transposition
+
+import (
+	"sync/atomic"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+// what a stored score says about the true score of the position
+type Bound uint8
+
+const (
+	// no entry, never stored
+	NoBound Bound = iota
+	// the score is exact
+	Exact
+	// the true score is at least the stored score (the search failed high)
+	Lower
+	// the true score is at most the stored score (the search failed low)
+	Upper
+)
+
+// search result stored for a position
+// Move is the zero Move when no best move is known
+type Entry struct {
+	Move  models.Move
+	Score int
+	Depth int
+	Bound Bound
+}
+
+// counters since the table was created or last cleared
+type Stats struct {
+	Probes uint64 `json:"probes"`
+	Hits   uint64 `json:"hits"`
+	Stores uint64 `json:"stores"`
+	// stores that evicted an entry for a different position written during the current search
+	Collisions uint64 `json:"collisions"`
+}
+
+// returns the fraction of probes that found an entry
+func (stats Stats) HitRate() float64 {
+	if stats.Probes == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Probes)
+}
+
+// entries per bucket, a bucket of 16 byte slots fills one 64 byte cache line
+const bucketSize = 4
+
+// a slot holds the packed entry and the position hash XORed with it,
+// so a reader that sees halves of two different writes fails the key check instead of returning a mixed entry
+type slot struct {
+	key  atomic.Uint64
+	data atomic.Uint64
+}
+
+type bucket [bucketSize]slot
+
+// fixed size hash table of search results keyed by Zobrist hash
+// safe for concurrent use: searches in parallel goroutines can share one table without locks
+type Table struct {
+	buckets    []bucket
+	mask       uint64
+	generation atomic.Uint32
+
+	probes     atomic.Uint64
+	hits       atomic.Uint64
+	stores     atomic.Uint64
+	collisions atomic.Uint64
+}
+
+// creates a table using at most sizeMB megabytes, rounded down to a power of two number of buckets
+func New(sizeMB int) *Table {
+	if sizeMB < 1 {
+		sizeMB = 1
+	}
+	count := uint64(sizeMB) * 1024 * 1024 / uint64(bucketSize*16)
+	// round down to a power of two so the bucket index is a mask of the hash
+	for count&(count-1) != 0 {
+		count &= count - 1
+	}
+	return &Table{
+		buckets: make([]bucket, count),
+		mask:    count - 1,
+	}
+}
+
+// returns the table's size in megabytes
+func (table *Table) SizeMB() int {
+	return len(table.buckets) * bucketSize * 16 / (1024 * 1024)
+}
+
+// marks the start of a new search, entries from earlier searches become the first to be replaced
+func (table *Table) NewSearch() {
+	table.generation.Add(1)
+}
+
+// removes every entry and resets the statistics
+// must not be called while the table is in use
+func (table *Table) Clear() {
+	for i := range table.buckets {
+		for j := range table.buckets[i] {
+			table.buckets[i][j].key.Store(0)
+			table.buckets[i][j].data.Store(0)
+		}
+	}
+	table.probes.Store(0)
+	table.hits.Store(0)
+	table.stores.Store(0)
+	table.collisions.Store(0)
+}
+
+// looks up the entry for a position
+func (table *Table) Probe(hash uint64) (Entry, bool) {
+	table.probes.Add(1)
+	bucket := &table.buckets[hash&table.mask]
+	for i := range bucket {
+		data := bucket[i].data.Load()
+		if data != 0 && bucket[i].key.Load()^data == hash {
+			table.hits.Add(1)
+			return unpack(data), true
+		}
+	}
+	return Entry{}, false
+}
+
+// stores the entry for a position
+// an existing entry for the same position is kept if it came from a deeper search of the current generation,
+// otherwise the entry replaced is the empty one or the one with the least depth, preferring those from earlier searches
+func (table *Table) Store(hash uint64, entry Entry) {
+	table.stores.Add(1)
+	generation := uint8(table.generation.Load()) & generationMask
+	data := pack(entry, generation)
+	bucket := &table.buckets[hash&table.mask]
+
+	empty, replace, worst := -1, 0, 0
+	for i := range bucket {
+		oldData := bucket[i].data.Load()
+		if oldData == 0 {
+			if empty < 0 {
+				empty = i
+			}
+			continue
+		}
+		if bucket[i].key.Load()^oldData == hash {
+			old := unpack(oldData)
+			if entryGeneration(oldData) == generation && old.Depth > entry.Depth && entry.Bound != Exact {
+				return
+			}
+			if entry.Move == (models.Move{}) {
+				// keep the known best move
+				data = pack(Entry{Move: old.Move, Score: entry.Score, Depth: entry.Depth, Bound: entry.Bound}, generation)
+			}
+			table.write(&bucket[i], hash, data)
+			return
+		}
+		// deep entries from the current search are worth the most
+		value := unpack(oldData).Depth
+		if entryGeneration(oldData) != generation {
+			value -= 256
+		}
+		if i == 0 || value < worst {
+			replace, worst = i, value
+		}
+	}
+
+	if empty >= 0 {
+		replace = empty
+	} else if entryGeneration(bucket[replace].data.Load()) == generation {
+		table.collisions.Add(1)
+	}
+	table.write(&bucket[replace], hash, data)
+}
+
+func (table *Table) write(slot *slot, hash, data uint64) {
+	slot.data.Store(data)
+	slot.key.Store(hash ^ data)
+}
+
+// returns the counters
+func (table *Table) Stats() Stats {
+	return Stats{
+		Probes:     table.probes.Load(),
+		Hits:       table.hits.Load(),
+		Stores:     table.stores.Load(),
+		Collisions: table.collisions.Load(),
+	}
+}
+
+// returns how full the table is in permille, counting only entries from the current search
+// samples the first thousand slots, as reported by UCI's hashfull
+func (table *Table) Usage() int {
+	generation := uint8(table.generation.Load()) & generationMask
+	used, total := 0, 0
+	for i := 0; i < len(table.buckets) && total < 1000; i++ {
+		for j := range table.buckets[i] {
+			data := table.buckets[i][j].data.Load()
+			if data != 0 && entryGeneration(data) == generation {
+				used++
+			}
+			total++
+		}
+	}
+	return used * 1000 / total
+}
@@ -0,0 +1,130 @@
+package transposition
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+)
+
+func TestPackRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Move: models.NewMove(models.Normal, 1, 4, 3, 4), Score: 35, Depth: 7, Bound: Exact},
+		{Move: models.NewMove(models.PromoteKnight, 6, 0, 7, 1), Score: -99990, Depth: 0, Bound: Upper},
+		{Move: models.NewMove(models.CastleLong, 7, 4, 7, 2), Score: 100000, Depth: 63, Bound: Lower},
+		{Score: -1, Depth: -1, Bound: Exact},
+	}
+	for _, entry := range entries {
+		for _, generation := range []uint8{0, 17, generationMask} {
+			data := pack(entry, generation)
+			if got := unpack(data); got != entry {
+				t.Errorf("unpack(pack(%+v)) = %+v", entry, got)
+			}
+			if got := entryGeneration(data); got != generation {
+				t.Errorf("generation %d came back as %d", generation, got)
+			}
+		}
+	}
+}
+
+func TestProbeAndStore(t *testing.T) {
+	table := New(1)
+	hash := uint64(0x123456789abcdef)
+	if _, ok := table.Probe(hash); ok {
+		t.Fatal("empty table returned an entry")
+	}
+
+	entry := Entry{Move: models.NewMove(models.Normal, 0, 6, 2, 5), Score: 12, Depth: 4, Bound: Exact}
+	table.Store(hash, entry)
+	if got, ok := table.Probe(hash); !ok || got != entry {
+		t.Errorf("probe = %+v, %v; want %+v", got, ok, entry)
+	}
+	// same bucket, different position
+	if _, ok := table.Probe(hash ^ 1<<63); ok {
+		t.Error("probe matched a different position")
+	}
+
+	// a shallower bound does not replace a deeper entry from the same search
+	table.Store(hash, Entry{Score: 50, Depth: 2, Bound: Lower})
+	if got, _ := table.Probe(hash); got != entry {
+		t.Errorf("shallow store replaced the entry, probe = %+v", got)
+	}
+	// a deeper result does, keeping the best move when it has none of its own
+	table.Store(hash, Entry{Score: 50, Depth: 6, Bound: Lower})
+	if got, _ := table.Probe(hash); got.Depth != 6 || got.Move != entry.Move {
+		t.Errorf("deep store gave %+v", got)
+	}
+
+	stats := table.Stats()
+	if stats.Probes != 5 || stats.Hits != 3 || stats.Stores != 3 {
+		t.Errorf("stats = %+v", stats)
+	}
+	table.Clear()
+	if _, ok := table.Probe(hash); ok || table.Stats().Stores != 0 {
+		t.Error("clear left entries or statistics behind")
+	}
+}
+
+func TestReplacement(t *testing.T) {
+	table := New(1)
+	buckets := uint64(len(table.buckets))
+	// hashes sharing bucket 0
+	hash := func(i int) uint64 { return uint64(i+1) * buckets }
+
+	for i := 0; i < bucketSize; i++ {
+		table.Store(hash(i), Entry{Score: i, Depth: 10 + i, Bound: Exact})
+	}
+	// the shallowest entry is replaced
+	table.Store(hash(bucketSize), Entry{Depth: 1, Bound: Exact})
+	if _, ok := table.Probe(hash(0)); ok {
+		t.Error("shallowest entry survived")
+	}
+	if table.Stats().Collisions != 1 {
+		t.Errorf("collisions = %d, want 1", table.Stats().Collisions)
+	}
+
+	// entries from earlier searches go first, however deep
+	table.NewSearch()
+	table.Store(hash(1), Entry{Depth: 11, Bound: Exact})
+	table.Store(hash(bucketSize+1), Entry{Depth: 1, Bound: Exact})
+	if _, ok := table.Probe(hash(1)); !ok {
+		t.Error("entry refreshed in the new search was replaced")
+	}
+	if _, ok := table.Probe(hash(bucketSize)); ok {
+		t.Error("old entry survived while refreshed entries were replaced")
+	}
+}
+
+func TestSize(t *testing.T) {
+	for _, size := range []int{1, 3, 16} {
+		table := New(size)
+		if got := table.SizeMB(); got > size || got*2 <= size {
+			t.Errorf("New(%d) made a %d MB table", size, got)
+		}
+	}
+}
+
+// run with -race: readers and writers share a table and readers must never see an entry stored for another position
+func TestConcurrentAccess(t *testing.T) {
+	table := New(1)
+	const positions = 1 << 12
+	// every entry's score identifies the position it was stored for
+	hash := func(i int) uint64 { return uint64(i)*0x9E3779B97F4A7C15 + 1 }
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 20000; i++ {
+				position := (i*7 + w) % positions
+				table.Store(hash(position), Entry{Score: position, Depth: i % 20, Bound: Exact})
+				if entry, ok := table.Probe(hash((i*13 + w) % positions)); ok && entry.Score != (i*13+w)%positions {
+					t.Errorf("read entry for position %d while probing %d", entry.Score, (i*13+w)%positions)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
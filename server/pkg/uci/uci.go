@@ -0,0 +1,360 @@
+package uci
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BrianJHenry/go-chess/server/pkg/engine"
+	"github.com/BrianJHenry/go-chess/server/pkg/models"
+	"github.com/BrianJHenry/go-chess/server/pkg/transposition"
+)
+
+const (
+	engineName   = "go-chess"
+	engineAuthor = "the go-chess authors"
+
+	defaultHashMB = 16
+	maxHashMB     = 1024
+
+	// moves assumed to be left in the game when the GUI does not send movestogo
+	defaultMovesToGo = 30
+	// kept back from every move's time for communication with the GUI
+	moveOverhead = 50 * time.Millisecond
+	// how often stop is repeated while waiting for the search to end
+	stopRetryInterval = 10 * time.Millisecond
+)
+
+// speaks the Universal Chess Interface to a GUI, running one search at a time in the background
+type Handler struct {
+	out     io.Writer
+	writeMu sync.Mutex
+
+	engine *engine.Engine
+	game   models.ChessGame
+	// move generator set on every position the handler searches
+	generator models.MoveGenerator
+
+	// closed when the running search has printed its best move, nil when no search was started
+	searchDone chan struct{}
+	// true if the running search only ends when stopped
+	searchUnbounded bool
+}
+
+// creates a handler writing its responses to out
+func NewHandler(out io.Writer) *Handler {
+	handler := &Handler{
+		out:    out,
+		engine: engine.New(),
+		game:   models.NewChessGame(),
+	}
+	handler.engine.Table = transposition.New(defaultHashMB)
+	return handler
+}
+
+// reads commands until quit or the end of the input
+func (handler *Handler) Run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if !handler.Execute(scanner.Text()) {
+			return nil
+		}
+	}
+	// a script piping commands in expects the answer to its last go, unless that search would never end
+	handler.finishSearch()
+	return scanner.Err()
+}
+
+// executes one command line and returns false once the handler should exit
+// unknown commands are ignored as the protocol requires
+func (handler *Handler) Execute(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+
+	switch fields[0] {
+	case "uci":
+		handler.printf("id name %s", engineName)
+		handler.printf("id author %s", engineAuthor)
+		handler.printf("option name Hash type spin default %d min 1 max %d", defaultHashMB, maxHashMB)
+		handler.printf("option name MoveGenerator type combo default mailbox var mailbox var bitboard")
+		handler.printf("uciok")
+	case "isready":
+		handler.printf("readyok")
+	case "ucinewgame":
+		handler.finishSearch()
+		handler.engine.Table.Clear()
+		handler.game = models.NewChessGame()
+		handler.game.CurrentState.SetMoveGenerator(handler.generator)
+	case "setoption":
+		handler.finishSearch()
+		if err := handler.setOption(fields[1:]); err != nil {
+			handler.printf("info string %v", err)
+		}
+	case "position":
+		handler.finishSearch()
+		if err := handler.setPosition(fields[1:]); err != nil {
+			handler.printf("info string %v", err)
+		}
+	case "go":
+		handler.finishSearch()
+		handler.startSearch(fields[1:])
+	case "stop":
+		handler.stopSearch()
+	case "quit":
+		handler.stopSearch()
+		return false
+	}
+	return true
+}
+
+// handles "setoption name <name> value <value>"
+func (handler *Handler) setOption(fields []string) error {
+	var name, value []string
+	var current *[]string
+	for _, field := range fields {
+		switch field {
+		case "name":
+			current = &name
+		case "value":
+			current = &value
+		default:
+			if current != nil {
+				*current = append(*current, field)
+			}
+		}
+	}
+
+	switch strings.ToLower(strings.Join(name, " ")) {
+	case "hash":
+		size, err := strconv.Atoi(strings.Join(value, " "))
+		if err != nil || size < 1 || size > maxHashMB {
+			return fmt.Errorf("invalid Hash value %q", strings.Join(value, " "))
+		}
+		handler.engine.Table = transposition.New(size)
+	case "movegenerator":
+		switch strings.ToLower(strings.Join(value, " ")) {
+		case "mailbox":
+			handler.generator = models.MailboxGenerator
+		case "bitboard":
+			handler.generator = models.BitboardGenerator
+		default:
+			return fmt.Errorf("invalid MoveGenerator value %q", strings.Join(value, " "))
+		}
+		handler.game.CurrentState.SetMoveGenerator(handler.generator)
+	default:
+		return fmt.Errorf("unknown option %q", strings.Join(name, " "))
+	}
+	return nil
+}
+
+// handles "position startpos|fen <fen> [moves <move>...]"
+// the position is left unchanged if any part of the command is invalid
+func (handler *Handler) setPosition(fields []string) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("position: missing startpos or fen")
+	}
+
+	var game models.ChessGame
+	var rest []string
+	switch fields[0] {
+	case "startpos":
+		game = models.NewChessGame()
+		rest = fields[1:]
+	case "fen":
+		end := len(fields)
+		for i, field := range fields {
+			if field == "moves" {
+				end = i
+				break
+			}
+		}
+		var err error
+		game, err = models.NewChessGameFromFEN(strings.Join(fields[1:end], " "))
+		if err != nil {
+			return err
+		}
+		rest = fields[end:]
+	default:
+		return fmt.Errorf("position: expected startpos or fen, found %q", fields[0])
+	}
+	game.CurrentState.SetMoveGenerator(handler.generator)
+
+	if len(rest) > 0 {
+		if rest[0] != "moves" {
+			return fmt.Errorf("position: expected moves, found %q", rest[0])
+		}
+		for _, uci := range rest[1:] {
+			move, err := game.CurrentState.ParseUCI(uci)
+			if err != nil {
+				return fmt.Errorf("position: %v", err)
+			}
+			game.ExecuteMoveOnGame(move)
+		}
+	}
+	handler.game = game
+	return nil
+}
+
+// handles "go" by starting a search in the background that prints info lines and finally the best move
+func (handler *Handler) startSearch(fields []string) {
+	limits := handler.searchLimits(fields)
+	handler.searchUnbounded = limits == engine.Limits{}
+	state := handler.game.CurrentState.Copy()
+	history := handler.game.PositionHistory()
+	done := make(chan struct{})
+	handler.searchDone = done
+
+	handler.engine.OnIteration = func(result engine.Result) {
+		handler.printInfo(result)
+	}
+	go func() {
+		defer close(done)
+		result := handler.engine.Search(state, history, limits)
+		if len(result.RootMoves) == 0 {
+			// no legal moves
+			handler.printf("bestmove 0000")
+			return
+		}
+		handler.printf("bestmove %s", result.BestMove.UCI())
+	}()
+}
+
+// converts the arguments of "go" to search limits
+// with a clock the move gets a share of the remaining time, depth, nodes and movetime are used as given
+func (handler *Handler) searchLimits(fields []string) engine.Limits {
+	var limits engine.Limits
+	var remaining, increment time.Duration
+	movesToGo := defaultMovesToGo
+
+	value := func(i int) int {
+		if i+1 >= len(fields) {
+			return 0
+		}
+		n, err := strconv.Atoi(fields[i+1])
+		if err != nil {
+			return 0
+		}
+		return n
+	}
+	white := handler.game.CurrentState.Turn == models.White
+	for i, field := range fields {
+		switch field {
+		case "depth":
+			limits.Depth = value(i)
+		case "nodes":
+			limits.Nodes = int64(value(i))
+		case "movetime":
+			limits.MoveTime = time.Duration(value(i)) * time.Millisecond
+		case "wtime":
+			if white {
+				remaining = time.Duration(value(i)) * time.Millisecond
+			}
+		case "btime":
+			if !white {
+				remaining = time.Duration(value(i)) * time.Millisecond
+			}
+		case "winc":
+			if white {
+				increment = time.Duration(value(i)) * time.Millisecond
+			}
+		case "binc":
+			if !white {
+				increment = time.Duration(value(i)) * time.Millisecond
+			}
+		case "movestogo":
+			if n := value(i); n > 0 {
+				movesToGo = n
+			}
+		}
+	}
+
+	if limits.MoveTime == 0 && remaining > 0 {
+		budget := remaining/time.Duration(movesToGo) + increment*3/4
+		if budget > remaining-moveOverhead {
+			budget = remaining - moveOverhead
+		}
+		if budget < time.Millisecond {
+			budget = time.Millisecond
+		}
+		limits.MoveTime = budget
+	} else if limits.MoveTime > moveOverhead {
+		limits.MoveTime -= moveOverhead
+	}
+	return limits
+}
+
+func (handler *Handler) printInfo(result engine.Result) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "info depth %d score %s nodes %d", result.Depth, formatScore(result.Score), result.Nodes)
+	if milliseconds := result.Time.Milliseconds(); milliseconds > 0 {
+		fmt.Fprintf(&sb, " nps %d", result.Nodes*1000/milliseconds)
+	}
+	fmt.Fprintf(&sb, " time %d", result.Time.Milliseconds())
+	if handler.engine.Table != nil {
+		fmt.Fprintf(&sb, " hashfull %d", handler.engine.Table.Usage())
+	}
+	if len(result.PV) > 0 {
+		sb.WriteString(" pv")
+		for _, move := range result.PV {
+			sb.WriteString(" " + move.UCI())
+		}
+	}
+	handler.printf("%s", sb.String())
+}
+
+// returns a score as "cp <centipawns>" or "mate <moves>", negative when the engine is being mated
+func formatScore(score int) string {
+	if !engine.IsMateScore(score) {
+		return fmt.Sprintf("cp %d", score)
+	}
+	if score > 0 {
+		return fmt.Sprintf("mate %d", (engine.MateScore-score+1)/2)
+	}
+	return fmt.Sprintf("mate %d", -(engine.MateScore+score)/2)
+}
+
+// stops the running search, returning after its best move has been printed
+func (handler *Handler) stopSearch() {
+	if handler.searchDone == nil {
+		return
+	}
+	// a search that has not started yet clears the request, so keep asking until it ends
+	for {
+		handler.engine.Stop()
+		select {
+		case <-handler.searchDone:
+			handler.searchDone = nil
+			return
+		case <-time.After(stopRetryInterval):
+		}
+	}
+}
+
+// ends the running search before a command that changes what it searches
+// a search that would never end on its own is stopped, any other is waited for
+func (handler *Handler) finishSearch() {
+	if handler.searchUnbounded {
+		handler.stopSearch()
+	}
+	handler.waitSearch()
+}
+
+// waits for the running search to finish on its own
+func (handler *Handler) waitSearch() {
+	if handler.searchDone != nil {
+		<-handler.searchDone
+		handler.searchDone = nil
+	}
+}
+
+func (handler *Handler) printf(format string, args ...interface{}) {
+	handler.writeMu.Lock()
+	defer handler.writeMu.Unlock()
+	fmt.Fprintf(handler.out, format+"\n", args...)
+}
@@ -0,0 +1,151 @@
+package uci
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collects the handler's output, safe for the search goroutine to write to
+type output struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (out *output) Write(p []byte) (int, error) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	return out.buf.Write(p)
+}
+
+func (out *output) lines() []string {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	return strings.Split(strings.TrimSpace(out.buf.String()), "\n")
+}
+
+func run(t *testing.T, script ...string) []string {
+	t.Helper()
+	out := &output{}
+	if err := NewHandler(out).Run(strings.NewReader(strings.Join(script, "\n"))); err != nil {
+		t.Fatal(err)
+	}
+	return out.lines()
+}
+
+// returns the last line starting with prefix, or "" if there is none
+func lastLine(lines []string, prefix string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], prefix) {
+			return lines[i]
+		}
+	}
+	return ""
+}
+
+func TestHandshake(t *testing.T) {
+	lines := run(t, "uci", "isready", "quit")
+	if lines[0] != "id name go-chess" || lastLine(lines, "uciok") == "" || lines[len(lines)-1] != "readyok" {
+		t.Errorf("unexpected handshake %q", lines)
+	}
+}
+
+func TestGoFindsMate(t *testing.T) {
+	tests := []struct {
+		name     string
+		position string
+		bestmove string
+		score    string
+	}{
+		{"fen", "position fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "bestmove a1a8", "score mate 1"},
+		{"fen with moves", "position fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1 moves g1h1 g8h8", "bestmove a1a8", "score mate 1"},
+		// 1. f3 e5 2. g4 Qh4#
+		{"startpos with moves", "position startpos moves f2f3 e7e5 g2g4", "bestmove d8h4", "score mate 1"},
+		{"checkmated", "position startpos moves f2f3 e7e5 g2g4 d8h4", "bestmove 0000", ""},
+	}
+	for _, test := range tests {
+		lines := run(t, test.position, "go depth 3", "quit")
+		if got := lastLine(lines, "bestmove"); got != test.bestmove {
+			t.Errorf("%s: got %q, want %q (output %q)", test.name, got, test.bestmove, lines)
+		}
+		if test.score != "" && !strings.Contains(lastLine(lines, "info depth"), test.score) {
+			t.Errorf("%s: last info %q does not contain %q", test.name, lastLine(lines, "info depth"), test.score)
+		}
+	}
+}
+
+func TestInvalidPositionIsReported(t *testing.T) {
+	lines := run(t, "position startpos moves e2e5", "position fen nonsense", "go depth 1", "quit")
+	if len(lastLine(lines, "info string")) == 0 {
+		t.Errorf("no error reported: %q", lines)
+	}
+	// the position stays at the start
+	if got := lastLine(lines, "bestmove"); got == "" || got == "bestmove 0000" {
+		t.Errorf("got %q from the starting position", got)
+	}
+}
+
+func TestStop(t *testing.T) {
+	out := &output{}
+	handler := NewHandler(out)
+	handler.Execute("position startpos")
+	handler.Execute("go infinite")
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	handler.Execute("stop")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stop took %v", elapsed)
+	}
+	if lastLine(out.lines(), "bestmove") == "" {
+		t.Errorf("no best move after stop: %q", out.lines())
+	}
+}
+
+func TestPositionStopsInfiniteSearch(t *testing.T) {
+	out := &output{}
+	handler := NewHandler(out)
+	handler.Execute("go infinite")
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.Execute("position startpos moves e2e4")
+		handler.Execute("go depth 1")
+		handler.Execute("quit")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("position blocked behind go infinite")
+	}
+	if lastLine(out.lines(), "bestmove") == "" {
+		t.Errorf("no best move: %q", out.lines())
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	lines := run(t, "setoption name Hash value 2", "setoption name MoveGenerator value bitboard", "setoption name Hash value lots", "go depth 2", "setoption name MoveGenerator value mailbox", "quit")
+	if len(lines) < 2 || !strings.Contains(lines[0], "invalid Hash value") {
+		t.Errorf("invalid option not reported first: %q", lines)
+	}
+	if lastLine(lines, "bestmove") == "" {
+		t.Errorf("no best move: %q", lines)
+	}
+}
+
+func TestClockAllocation(t *testing.T) {
+	handler := NewHandler(&output{})
+	limits := handler.searchLimits(strings.Fields("wtime 60000 btime 1000 winc 1000 binc 0"))
+	if limits.MoveTime < time.Second || limits.MoveTime > 5*time.Second {
+		t.Errorf("white with a minute got %v", limits.MoveTime)
+	}
+	handler.Execute("position startpos moves e2e4")
+	limits = handler.searchLimits(strings.Fields("wtime 60000 btime 40 winc 1000 binc 0"))
+	if limits.MoveTime <= 0 || limits.MoveTime > 40*time.Millisecond {
+		t.Errorf("black with 40ms got %v", limits.MoveTime)
+	}
+}